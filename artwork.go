@@ -0,0 +1,541 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"image"
+	"image/color"
+	"image/color/palette"
+	"image/draw"
+	_ "image/gif"
+	_ "image/jpeg"
+	"image/png"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+const (
+	// artworkCols and artworkRows size the artwork in terminal cells
+	artworkCols = 20
+	artworkRows = 10
+	// artworkCellWidth and artworkCellHeight approximate a cell in pixels for the kitty protocol
+	artworkCellWidth  = 8
+	artworkCellHeight = 16
+	// maxArtworkBytes keeps a misconfigured logo URL from downloading something huge
+	maxArtworkBytes = 2 << 20
+	// artworkTimeout bounds fetching one logo
+	artworkTimeout = 10 * time.Second
+)
+
+// ArtworkMode selects how station artwork is drawn
+type ArtworkMode string
+
+const (
+	ArtworkAuto      ArtworkMode = "auto" // Best the terminal is known to support
+	ArtworkHalfBlock ArtworkMode = "halfblock"
+	ArtworkKitty     ArtworkMode = "kitty"
+	ArtworkSixel     ArtworkMode = "sixel"
+	ArtworkOff       ArtworkMode = "off"
+)
+
+// detectArtworkMode picks a mode from what the environment says about the terminal
+func detectArtworkMode(getenv func(string) string) ArtworkMode {
+	term, program := getenv("TERM"), getenv("TERM_PROGRAM")
+	switch {
+	case getenv("NO_COLOR") != "" || term == "dumb" || term == "":
+		return ArtworkOff
+	case getenv("KITTY_WINDOW_ID") != "" || term == "xterm-kitty" || program == "ghostty" || program == "WezTerm":
+		return ArtworkKitty
+	case strings.HasPrefix(term, "foot") || strings.HasPrefix(term, "mlterm") || strings.Contains(term, "sixel"):
+		return ArtworkSixel
+	case getenv("COLORTERM") == "truecolor" || getenv("COLORTERM") == "24bit" || strings.Contains(term, "256color"):
+		return ArtworkHalfBlock
+	}
+	return ArtworkOff
+}
+
+// resolve turns auto into a concrete mode for the current terminal. Modes this
+// build does not know count as auto.
+func (m ArtworkMode) resolve() ArtworkMode {
+	switch m {
+	case ArtworkHalfBlock, ArtworkKitty, ArtworkSixel, ArtworkOff:
+		return m
+	}
+	return detectArtworkMode(os.Getenv)
+}
+
+// isLocalArtwork reports whether a logo refers to a file rather than a URL
+func isLocalArtwork(src string) bool {
+	return !strings.HasPrefix(src, "http://") && !strings.HasPrefix(src, "https://")
+}
+
+// artworkCachePath returns where a downloaded logo is kept
+func artworkCachePath(src string) (string, error) {
+	dir, err := CacheDir()
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256([]byte(src))
+	return filepath.Join(dir, "artwork", hex.EncodeToString(sum[:12])), nil
+}
+
+// LoadArtwork reads a station logo from a local path, or from the disk cache,
+// downloading it into the cache the first time
+func LoadArtwork(src string) (image.Image, error) {
+	var data []byte
+	var err error
+	if isLocalArtwork(src) {
+		data, err = os.ReadFile(expandHome(strings.TrimPrefix(src, "file://")))
+	} else {
+		data, err = cachedArtwork(src)
+	}
+	if err != nil {
+		return nil, err
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("artwork %s: %v", src, err)
+	}
+	return img, nil
+}
+
+// cachedArtwork returns a downloaded logo, fetching it when it is not cached yet
+func cachedArtwork(src string) ([]byte, error) {
+	path, err := artworkCachePath(src)
+	if err != nil {
+		return nil, err
+	}
+	if data, err := os.ReadFile(path); err == nil {
+		return data, nil
+	}
+
+	client := &http.Client{Timeout: artworkTimeout}
+	req, err := http.NewRequest("GET", src, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "goradio-hub/"+Version)
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("artwork: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("artwork %s: server answered %s", src, resp.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxArtworkBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("artwork: %v", err)
+	}
+	if len(data) > maxArtworkBytes {
+		return nil, fmt.Errorf("artwork %s: larger than %d bytes", src, maxArtworkBytes)
+	}
+
+	// A cache that cannot be written only costs another download next time
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err == nil {
+		err = writeFileAtomic(path, data, 0644)
+	}
+	if err != nil {
+		log.Printf("artwork: caching %s: %v", src, err)
+	}
+	return data, nil
+}
+
+// RenderArtwork draws an image in a box of artworkCols by artworkRows cells
+// as half blocks, or returns "" when the mode draws nothing. Kitty and sixel
+// images are drawn outside the text layout by handleArtwork instead.
+func RenderArtwork(img image.Image, mode ArtworkMode) string {
+	if mode == ArtworkHalfBlock {
+		return renderHalfBlock(img, artworkCols, artworkRows)
+	}
+	return ""
+}
+
+// scaleImage fits img into w by h pixels, keeping its aspect ratio, by
+// averaging the source pixels that fall into each target pixel
+func scaleImage(img image.Image, w, h int) *image.NRGBA {
+	b := img.Bounds()
+	if b.Dx() == 0 || b.Dy() == 0 {
+		return image.NewNRGBA(image.Rect(0, 0, 1, 1))
+	}
+	if b.Dx()*h > b.Dy()*w {
+		h = max(1, b.Dy()*w/b.Dx())
+	} else {
+		w = max(1, b.Dx()*h/b.Dy())
+	}
+
+	out := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		y0, y1 := b.Min.Y+y*b.Dy()/h, b.Min.Y+(y+1)*b.Dy()/h
+		for x := 0; x < w; x++ {
+			x0, x1 := b.Min.X+x*b.Dx()/w, b.Min.X+(x+1)*b.Dx()/w
+			var r, g, bl, a, n uint64
+			for sy := y0; sy < max(y1, y0+1); sy++ {
+				for sx := x0; sx < max(x1, x0+1); sx++ {
+					c := color.NRGBA64Model.Convert(img.At(sx, sy)).(color.NRGBA64)
+					r, g, bl, a = r+uint64(c.R), g+uint64(c.G), bl+uint64(c.B), a+uint64(c.A)
+					n++
+				}
+			}
+			out.SetNRGBA(x, y, color.NRGBA{
+				R: uint8(r / n >> 8), G: uint8(g / n >> 8), B: uint8(bl / n >> 8), A: uint8(a / n >> 8),
+			})
+		}
+	}
+	return out
+}
+
+// hexColor formats an opaque color for lipgloss
+func hexColor(c color.NRGBA) lipgloss.Color {
+	return lipgloss.Color(fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B))
+}
+
+// renderHalfBlock draws two pixels per cell with "▀": the upper one in the
+// foreground color and the lower one in the background color. Transparent
+// pixels are left to the terminal's own background.
+func renderHalfBlock(img image.Image, cols, rows int) string {
+	px := scaleImage(img, cols, rows*2)
+	w, h := px.Bounds().Dx(), px.Bounds().Dy()
+
+	lines := make([]string, 0, (h+1)/2)
+	for y := 0; y < h; y += 2 {
+		var line strings.Builder
+		for x := 0; x < w; x++ {
+			top := px.NRGBAAt(x, y)
+			bottom := color.NRGBA{}
+			if y+1 < h {
+				bottom = px.NRGBAAt(x, y+1)
+			}
+			switch {
+			case top.A < 128 && bottom.A < 128:
+				line.WriteString(" ")
+			case bottom.A < 128:
+				line.WriteString(lipgloss.NewStyle().Foreground(hexColor(top)).Render("▀"))
+			case top.A < 128:
+				line.WriteString(lipgloss.NewStyle().Foreground(hexColor(bottom)).Render("▄"))
+			default:
+				line.WriteString(lipgloss.NewStyle().Foreground(hexColor(top)).Background(hexColor(bottom)).Render("▀"))
+			}
+		}
+		lines = append(lines, line.String())
+	}
+	return strings.Join(lines, "\n")
+}
+
+// reserveCells returns blank lines covering the area an inline image is drawn over,
+// with the image escape sequence in front of the first one
+func reserveCells(escape string, cols, rows int) string {
+	lines := make([]string, rows)
+	for i := range lines {
+		lines[i] = strings.Repeat(" ", cols)
+	}
+	lines[0] = escape + lines[0]
+	return strings.Join(lines, "\n")
+}
+
+// sixelMarker stands in the first cell reserved for a sixel image, so placeSixel
+// can find where the layout put it. It is one cell wide, like the blank it becomes.
+const sixelMarker = "\ue000"
+
+// cellsFor returns how many cells an image of the given pixel size covers
+func cellsFor(px *image.NRGBA) (int, int) {
+	b := px.Bounds()
+	return (b.Dx() + artworkCellWidth - 1) / artworkCellWidth, (b.Dy() + artworkCellHeight - 1) / artworkCellHeight
+}
+
+// kittyImageID derives the image id a logo is uploaded under, so the same
+// logo keeps its id for the whole session. Zero is not a valid id.
+func kittyImageID(src string) uint32 {
+	sum := sha256.Sum256([]byte(src))
+	id := uint32(sum[0])<<24 | uint32(sum[1])<<16 | uint32(sum[2])<<8 | uint32(sum[3])
+	if id == 0 {
+		id = 1
+	}
+	return id
+}
+
+// kittyUpload encodes the image for the kitty graphics protocol, fitted into
+// cols by rows cells, returning the escapes that store it in the terminal
+// under id without showing it, and the cells it covers
+func kittyUpload(img image.Image, id uint32, cols, rows int) (string, int, int) {
+	px := scaleImage(img, cols*artworkCellWidth, rows*artworkCellHeight)
+	cols, rows = cellsFor(px)
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, px); err != nil {
+		return "", 0, 0
+	}
+	payload := base64.StdEncoding.EncodeToString(buf.Bytes())
+
+	// Payloads are sent in chunks of at most 4096 bytes
+	var esc strings.Builder
+	for first := true; payload != ""; first = false {
+		chunk := payload[:min(4096, len(payload))]
+		payload = payload[len(chunk):]
+		more := 0
+		if payload != "" {
+			more = 1
+		}
+		if first {
+			fmt.Fprintf(&esc, "\x1b_Ga=t,f=100,i=%d,q=2,m=%d;%s\x1b\\", id, more, chunk)
+		} else {
+			fmt.Fprintf(&esc, "\x1b_Gm=%d;%s\x1b\\", more, chunk)
+		}
+	}
+	return esc.String(), cols, rows
+}
+
+// kittyPlacement shows an uploaded image at the cursor over blank cells. Every
+// placement uses placement id 1, so drawing it again moves it rather than
+// adding a copy, and the cursor is left in place so the text layout continues.
+func kittyPlacement(id uint32, cols, rows int) string {
+	return reserveCells(fmt.Sprintf("\x1b_Ga=p,i=%d,p=1,c=%d,r=%d,C=1,q=2\x1b\\", id, cols, rows), cols, rows)
+}
+
+// renderSixel encodes the image as DEC sixel graphics using the web-safe
+// palette, fitted into cols by rows cells, returning the escape and the cells it covers
+func renderSixel(img image.Image, cols, rows int) (string, int, int) {
+	px := scaleImage(img, cols*artworkCellWidth, rows*artworkCellHeight)
+	cols, rows = cellsFor(px)
+	b := px.Bounds()
+	pal := image.NewPaletted(b, palette.WebSafe)
+	draw.FloydSteinberg.Draw(pal, b, px, image.Point{})
+
+	var esc strings.Builder
+	// P2=1 leaves transparent pixels alone
+	fmt.Fprintf(&esc, "\x1bP0;1;0q\"1;1;%d;%d", b.Dx(), b.Dy())
+	for i, c := range palette.WebSafe {
+		r, g, bl, _ := c.RGBA()
+		fmt.Fprintf(&esc, "#%d;2;%d;%d;%d", i, r*100/0xffff, g*100/0xffff, bl*100/0xffff)
+	}
+
+	for band := 0; band < b.Dy(); band += 6 {
+		used := make(map[uint8]bool)
+		for y := band; y < min(band+6, b.Dy()); y++ {
+			for x := 0; x < b.Dx(); x++ {
+				if px.NRGBAAt(x, y).A >= 128 {
+					used[pal.ColorIndexAt(x, y)] = true
+				}
+			}
+		}
+		for index := range used {
+			fmt.Fprintf(&esc, "#%d", index)
+			var run byte
+			count := 0
+			flush := func() {
+				switch {
+				case count > 3:
+					fmt.Fprintf(&esc, "!%d%c", count, run)
+				case count > 0:
+					esc.WriteString(strings.Repeat(string(run), count))
+				}
+			}
+			for x := 0; x < b.Dx(); x++ {
+				var bits byte
+				for dy := 0; dy < 6 && band+dy < b.Dy(); dy++ {
+					if px.NRGBAAt(x, band+dy).A >= 128 && pal.ColorIndexAt(x, band+dy) == index {
+						bits |= 1 << dy
+					}
+				}
+				char := 63 + bits
+				if char == run {
+					count++
+					continue
+				}
+				flush()
+				run, count = char, 1
+			}
+			flush()
+			esc.WriteString("$")
+		}
+		esc.WriteString("-")
+	}
+	esc.WriteString("\x1b\\")
+	return esc.String(), cols, rows
+}
+
+// kittyHide removes the placements of an uploaded image, keeping its data
+// so it can be shown again without another upload
+func kittyHide(id uint32) string {
+	return fmt.Sprintf("\x1b_Ga=d,d=i,i=%d,q=2\x1b\\", id)
+}
+
+// artworkEntry is a logo that has been loaded, or is being loaded
+type artworkEntry struct {
+	loading    bool
+	rendered   string
+	kittyID    uint32 // Set once the image is uploaded to the terminal
+	sixel      string // Sixel image drawn over the cells rendered reserves
+	cols, rows int    // Cells the sixel image covers
+}
+
+// artworkMsg delivers a loaded station logo
+type artworkMsg struct {
+	src string
+	img image.Image
+	err error
+}
+
+// loadArtworkCmd fetches and decodes a logo in the background
+func loadArtworkCmd(src string) tea.Cmd {
+	return func() tea.Msg {
+		img, err := LoadArtwork(src)
+		return artworkMsg{src: src, img: img, err: err}
+	}
+}
+
+// infoStation returns the station the info panel describes: the playing one, else the highlighted one
+func (m Model) infoStation() *RadioStation {
+	if station := m.player.GetCurrentStation(); station != nil {
+		return station
+	}
+	return m.selectedStation()
+}
+
+// artworkCmd starts loading the shown station's logo unless it is loaded or loading already
+func (m Model) artworkCmd() tea.Cmd {
+	station := m.infoStation()
+	if station == nil || station.Logo == "" || m.artworkMode == ArtworkOff {
+		return nil
+	}
+	if _, ok := m.artwork[station.Logo]; ok {
+		return nil
+	}
+	m.artwork[station.Logo] = &artworkEntry{loading: true}
+	return loadArtworkCmd(station.Logo)
+}
+
+// artworkUploadedMsg reports that a logo is stored in the terminal and can be placed
+type artworkUploadedMsg struct {
+	src        string
+	id         uint32
+	cols, rows int
+}
+
+// uploadArtworkCmd sends a kitty image to the terminal once, outside the frames
+// the renderer draws, so redrawn lines only carry the small placement escape
+func uploadArtworkCmd(out *terminalOutput, src string, img image.Image) tea.Cmd {
+	return func() tea.Msg {
+		id := kittyImageID(src)
+		upload, cols, rows := kittyUpload(img, id, artworkCols, artworkRows)
+		if upload == "" {
+			return artworkUploadedMsg{src: src}
+		}
+		// The output's lock keeps frames from landing inside the escape
+		if _, err := out.WriteString(upload); err != nil {
+			log.Printf("artwork: uploading %s: %v", src, err)
+			return artworkUploadedMsg{src: src}
+		}
+		return artworkUploadedMsg{src: src, id: id, cols: cols, rows: rows}
+	}
+}
+
+// handleArtwork renders a loaded logo once so later frames reuse it
+func (m Model) handleArtwork(msg artworkMsg) (tea.Model, tea.Cmd) {
+	entry := &artworkEntry{}
+	m.artwork[msg.src] = entry
+	if msg.err != nil {
+		log.Printf("artwork: %v", msg.err)
+		return m, nil
+	}
+	if m.artworkMode == ArtworkKitty {
+		entry.loading = true
+		return m, uploadArtworkCmd(m.output, msg.src, msg.img)
+	}
+	if m.artworkMode == ArtworkSixel {
+		entry.sixel, entry.cols, entry.rows = renderSixel(msg.img, artworkCols, artworkRows)
+		entry.rendered = strings.Replace(reserveCells("", entry.cols, entry.rows), " ", sixelMarker, 1)
+		return m, nil
+	}
+	entry.rendered = RenderArtwork(msg.img, m.artworkMode)
+	return m, nil
+}
+
+// handleArtworkUploaded starts placing a logo once the terminal has it
+func (m Model) handleArtworkUploaded(msg artworkUploadedMsg) (tea.Model, tea.Cmd) {
+	entry := &artworkEntry{}
+	if msg.id != 0 {
+		entry.kittyID = msg.id
+		entry.rendered = kittyPlacement(msg.id, msg.cols, msg.rows)
+	}
+	m.artwork[msg.src] = entry
+	return m, nil
+}
+
+// hiddenArtwork returns the escapes removing every uploaded logo except the
+// shown one from the screen. Text drawn over a kitty image does not erase it,
+// so a logo stays until it is deleted.
+func (m Model) hiddenArtwork(shown string) string {
+	var ids []int
+	for src, entry := range m.artwork {
+		if entry.kittyID != 0 && src != shown {
+			ids = append(ids, int(entry.kittyID))
+		}
+	}
+	// A steady order keeps unchanged frames identical, so the renderer skips them
+	sort.Ints(ids)
+	var b strings.Builder
+	for _, id := range ids {
+		b.WriteString(kittyHide(uint32(id)))
+	}
+	return b.String()
+}
+
+// placeSixel finds the cells reserved for the shown sixel logo in a finished
+// frame, blanks the marker and has the output draw the image over them after
+// the frame. The renderer rewriting a line erases the part of the image on it,
+// so the image is sent again only when the lines it covers change, not on
+// every frame.
+func (m Model) placeSixel(frame, shown string) string {
+	if m.artworkMode != ArtworkSixel || m.output == nil {
+		return frame
+	}
+	entry := m.artwork[shown]
+	lines := strings.Split(frame, "\n")
+	for i, line := range lines {
+		at := strings.Index(line, sixelMarker)
+		if at < 0 {
+			continue
+		}
+		lines[i] = line[:at] + " " + line[at+len(sixelMarker):]
+		// The renderer drops the top lines of a frame taller than the terminal
+		row, col := i+1, lipgloss.Width(line[:at])+1
+		if m.height > 0 {
+			row -= max(0, len(lines)-m.height)
+		}
+		fits := entry != nil && row >= 1 && i+entry.rows <= len(lines) &&
+			(m.height == 0 || row+entry.rows-1 <= m.height) && (m.width == 0 || col+entry.cols-1 <= m.width)
+		if !fits || entry.sixel == "" {
+			m.output.setOverlay("", "")
+		} else {
+			// Save the cursor, draw at the reserved cells and put the cursor back
+			key := fmt.Sprintf("%d;%d;%s\n%s", row, col, shown, strings.Join(lines[i:i+entry.rows], "\n"))
+			m.output.setOverlay(key, fmt.Sprintf("\x1b7\x1b[%d;%dH%s\x1b8", row, col, entry.sixel))
+		}
+		return strings.Join(lines, "\n")
+	}
+	m.output.setOverlay("", "")
+	return frame
+}
+
+// stationArtwork returns the rendered logo of a station, or "" when there is none to show
+func (m Model) stationArtwork(station *RadioStation) string {
+	if station == nil || station.Logo == "" {
+		return ""
+	}
+	if entry, ok := m.artwork[station.Logo]; ok {
+		return entry.rendered
+	}
+	return ""
+}
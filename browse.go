@@ -0,0 +1,302 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// browseRows is how many search results are shown at once
+const browseRows = 12
+
+const (
+	queryName = iota
+	queryTag
+	queryCountry
+	queryLanguage
+	queryCodec
+)
+
+// directoryBrowser is the view for searching the station directory
+type directoryBrowser struct {
+	fields  []formField
+	focus   int
+	order   DirectoryOrder
+	editing bool // Typing in the query fields rather than moving through results
+
+	seq     int // Identifies the latest search so stale answers are dropped
+	loading bool
+	err     error
+	results []DirectoryStation
+	cursor  int
+	start   int
+}
+
+// directoryResultsMsg delivers the answer to a directory search
+type directoryResultsMsg struct {
+	seq     int
+	results []DirectoryStation
+	err     error
+}
+
+// newDirectoryBrowser opens the browser with an empty query
+func newDirectoryBrowser() *directoryBrowser {
+	return &directoryBrowser{
+		fields: []formField{
+			{label: "Name"},
+			{label: "Tag"},
+			{label: "Country"},
+			{label: "Language"},
+			{label: "Codec"},
+		},
+		order:   OrderVotes,
+		editing: true,
+	}
+}
+
+// query builds a directory search from the fields
+func (b *directoryBrowser) query() DirectoryQuery {
+	value := func(field int) string {
+		return strings.TrimSpace(string(b.fields[field].value))
+	}
+	return DirectoryQuery{
+		Name:     value(queryName),
+		Tag:      value(queryTag),
+		Country:  value(queryCountry),
+		Language: value(queryLanguage),
+		Codec:    value(queryCodec),
+		Order:    b.order,
+	}
+}
+
+// search starts a new query, superseding any still in flight
+func (b *directoryBrowser) search(client *DirectoryClient) tea.Cmd {
+	b.seq++
+	b.loading = true
+	b.err = nil
+	seq, q := b.seq, b.query()
+	return func() tea.Msg {
+		results, err := client.Search(q)
+		return directoryResultsMsg{seq: seq, results: results, err: err}
+	}
+}
+
+// selected returns the highlighted result, or nil when there are none
+func (b *directoryBrowser) selected() *DirectoryStation {
+	if b.cursor < 0 || b.cursor >= len(b.results) {
+		return nil
+	}
+	return &b.results[b.cursor]
+}
+
+// moveCursor moves the highlight by delta, scrolling the result window with it
+func (b *directoryBrowser) moveCursor(delta int) {
+	b.cursor = max(0, min(len(b.results)-1, b.cursor+delta))
+	if b.cursor < b.start {
+		b.start = b.cursor
+	} else if b.cursor >= b.start+browseRows {
+		b.start = b.cursor - browseRows + 1
+	}
+}
+
+// handleQueryKey edits the focused query field; it returns true when the key was consumed
+func (b *directoryBrowser) handleQueryKey(msg tea.KeyMsg) bool {
+	field := &b.fields[b.focus]
+	switch msg.Type {
+	case tea.KeyTab, tea.KeyDown:
+		b.focus = (b.focus + 1) % len(b.fields)
+	case tea.KeyShiftTab, tea.KeyUp:
+		b.focus = (b.focus + len(b.fields) - 1) % len(b.fields)
+	case tea.KeyBackspace:
+		if len(field.value) > 0 {
+			field.value = field.value[:len(field.value)-1]
+		}
+	case tea.KeyCtrlU:
+		field.value = nil
+	case tea.KeySpace:
+		field.value = append(field.value, ' ')
+	case tea.KeyRunes:
+		field.value = append(field.value, msg.Runes...)
+	default:
+		return false
+	}
+	return true
+}
+
+// RenderDirectoryBrowser renders the search fields and the result list
+func RenderDirectoryBrowser(b *directoryBrowser) string {
+	muted := lipgloss.NewStyle().Foreground(mutedColor)
+	content := []string{titleStyle.Render("Station Directory")}
+
+	for i, field := range b.fields {
+		label := fmt.Sprintf("%-10s", field.label+":")
+		value := string(field.value)
+		if b.editing && i == b.focus {
+			content = append(content, selectedItemStyle.Render(label+value+"█"))
+		} else {
+			content = append(content, normalItemStyle.Render(label+value))
+		}
+	}
+	content = append(content, muted.Render("Sorted by "+b.order.String()), "")
+
+	switch {
+	case b.loading:
+		content = append(content, "Searching...")
+	case b.err != nil:
+		content = append(content, lipgloss.NewStyle().Foreground(accentColor).Render(firstLine(b.err.Error())))
+	case b.seq > 0 && len(b.results) == 0:
+		content = append(content, "No stations found")
+	default:
+		end := min(len(b.results), b.start+browseRows)
+		for i := b.start; i < end; i++ {
+			line := formatDirectoryResult(b.results[i])
+			if !b.editing && i == b.cursor {
+				line = selectedItemStyle.Render("▶ " + line)
+			} else {
+				line = normalItemStyle.Render("  " + line)
+			}
+			content = append(content, line)
+		}
+		if len(b.results) > browseRows {
+			content = append(content, muted.Render(fmt.Sprintf("%d-%d of %d", b.start+1, end, len(b.results))))
+		}
+	}
+
+	help := []string{
+		"Tab/↑/↓  Next/previous field",
+		"Enter    Search",
+		"Ctrl+O   Sort by votes/clicks",
+		"Esc      Back to results / close",
+	}
+	if !b.editing {
+		help = []string{
+			"Enter  Play",
+			"s      Save to library",
+			"/      Edit search",
+			"o      Sort by votes/clicks",
+			"Esc    Close",
+		}
+	}
+	content = append(content, "", muted.Render(strings.Join(help, "\n")))
+
+	return strings.Join(content, "\n")
+}
+
+// formatDirectoryResult summarizes a result on one line
+func formatDirectoryResult(d DirectoryStation) string {
+	parts := []string{truncate(strings.TrimSpace(d.Name), 28)}
+	if d.CountryCode != "" {
+		parts = append(parts, d.CountryCode)
+	}
+	if codec := directoryCodec(d.Codec); codec != "" || d.Bitrate > 0 {
+		parts = append(parts, StreamVariant{Codec: codec, Bitrate: d.Bitrate}.String())
+	}
+	parts = append(parts, fmt.Sprintf("♥%d", d.Votes))
+	return strings.Join(parts, " · ")
+}
+
+// truncate shortens s to at most n runes, marking the cut with an ellipsis
+func truncate(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n-1]) + "…"
+}
+
+// updateBrowser handles keys while the directory browser is open
+func (m Model) updateBrowser(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	b := m.browser
+	client := NewDirectoryClient(m.config.DirectoryURL)
+
+	if b.editing {
+		switch msg.Type {
+		case tea.KeyEsc:
+			if len(b.results) == 0 {
+				m.browser = nil
+			} else {
+				b.editing = false
+			}
+			return m, nil
+		case tea.KeyEnter:
+			b.editing = false
+			b.cursor, b.start = 0, 0
+			return m, b.search(client)
+		case tea.KeyCtrlO:
+			b.order = b.order.Next()
+			return m, nil
+		}
+		b.handleQueryKey(msg)
+		return m, nil
+	}
+
+	switch msg.String() {
+	case "esc", "q":
+		m.browser = nil
+
+	case "/":
+		b.editing = true
+
+	case "up", "k":
+		b.moveCursor(-1)
+
+	case "down", "j":
+		b.moveCursor(1)
+
+	case "pgup":
+		b.moveCursor(-browseRows)
+
+	case "pgdown":
+		b.moveCursor(browseRows)
+
+	case "o":
+		b.order = b.order.Next()
+		b.cursor, b.start = 0, 0
+		return m, b.search(client)
+
+	case "enter", " ":
+		if result := b.selected(); result != nil {
+			station := result.Station()
+			m.play(&station)
+		}
+
+	case "s":
+		result := b.selected()
+		if result == nil {
+			break
+		}
+		station := result.Station()
+		imported, err := ImportStations([]RadioStation{station})
+		switch {
+		case err != nil:
+			m.statusMsg = "⚠ " + firstLine(err.Error())
+		case imported.Added == 0 && imported.Duplicates > 0:
+			m.statusMsg = fmt.Sprintf("%q is already in the library", station.Name)
+		case imported.Added == 0:
+			m.statusMsg = fmt.Sprintf("%q has no usable stream URL", station.Name)
+		default:
+			key := ""
+			if selected := m.selectedStation(); selected != nil {
+				key = selected.Key()
+			}
+			m.includeInProfile(imported.Keys...)
+			m.reloadStations(key)
+			m.statusMsg = fmt.Sprintf("Saved %q to the library", station.Name)
+		}
+	}
+	return m, nil
+}
+
+// handleDirectoryResults shows a search answer if it belongs to the latest search
+func (m Model) handleDirectoryResults(msg directoryResultsMsg) (tea.Model, tea.Cmd) {
+	b := m.browser
+	if b == nil || msg.seq != b.seq {
+		return m, nil
+	}
+	b.loading = false
+	b.results = msg.results
+	b.err = msg.err
+	return m, nil
+}
@@ -0,0 +1,288 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// cliCommand is a subcommand runnable without starting the TUI
+type cliCommand struct {
+	name    string
+	summary string
+	run     func(args []string) int
+}
+
+// cliCommands lists the available subcommands
+func cliCommands() []cliCommand {
+	return []cliCommand{
+		{"import", "add stations from M3U/M3U8/PLS/OPML files to the library", runImport},
+		{"export", "write the library (or a filtered part) as M3U, PLS or OPML", runExport},
+		{"search", "search the Radio Browser station directory", runSearch},
+		{"sync", "refresh the SomaFM channel catalog", runSync},
+		{"check", "probe every station and report which are dead", runCheck},
+		{"dupes", "list stations that play the same stream", runDupes},
+	}
+}
+
+// runCLI dispatches a subcommand and returns the process exit code
+func runCLI(args []string) int {
+	for _, cmd := range cliCommands() {
+		if cmd.name == args[0] {
+			return cmd.run(args[1:])
+		}
+	}
+
+	fmt.Fprintf(os.Stderr, "goradio: unknown command %q\n\nCommands:\n", args[0])
+	for _, cmd := range cliCommands() {
+		fmt.Fprintf(os.Stderr, "  %-10s %s\n", cmd.name, cmd.summary)
+	}
+	return 2
+}
+
+// runImport implements "goradio import FILE..."
+func runImport(args []string) int {
+	fs := flag.NewFlagSet("import", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: goradio import FILE...")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return 2
+	}
+
+	status := 0
+	for _, name := range fs.Args() {
+		stations, err := ParseStationList(name)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "goradio: %v\n", err)
+			status = 1
+			continue
+		}
+		result, err := ImportStations(stations)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "goradio: importing %s: %v\n", name, err)
+			status = 1
+			continue
+		}
+		fmt.Printf("%s: %s\n", name, result)
+	}
+	return status
+}
+
+// runExport implements "goradio export [-format m3u|pls|opml] [-genre G] [-tags FILTER] [-o FILE]"
+func runExport(args []string) int {
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	format := fs.String("format", "", "output format: m3u, pls or opml (default: from -o extension, else m3u)")
+	genre := fs.String("genre", "", "only export stations with this genre or tag")
+	tags := fs.String("tags", "", "only export stations matching a tag filter: a+b for both, a,b for either")
+	output := fs.String("o", "", "write to this file instead of stdout")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	stations, err := LoadStations()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "goradio: %v\n", err)
+		return 1
+	}
+	if *genre != "" {
+		var filtered []RadioStation
+		for _, s := range stations {
+			if s.HasTag(*genre) {
+				filtered = append(filtered, s)
+			}
+		}
+		stations = filtered
+	}
+	stations = ParseTagFilter(*tags).Apply(stations)
+
+	if *format == "" {
+		*format = "m3u"
+		if *output != "" {
+			if f, err := playlistFormatFor(*output, nil); err == nil {
+				*format = string(f)
+			}
+		}
+	}
+
+	data, err := FormatStationList(strings.ToLower(*format), stations)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "goradio: %v\n", err)
+		return 2
+	}
+
+	if *output == "" {
+		os.Stdout.Write(data)
+		return 0
+	}
+	if err := writeFileAtomic(*output, data, 0644); err != nil {
+		fmt.Fprintf(os.Stderr, "goradio: %v\n", err)
+		return 1
+	}
+	fmt.Fprintf(os.Stderr, "Exported %d stations to %s\n", len(stations), *output)
+	return 0
+}
+
+// runSearch implements "goradio search [-name N] [-tag T] ... [-save]"
+func runSearch(args []string) int {
+	fs := flag.NewFlagSet("search", flag.ContinueOnError)
+	var q DirectoryQuery
+	fs.StringVar(&q.Name, "name", "", "station name contains")
+	fs.StringVar(&q.Tag, "tag", "", "station has this tag")
+	fs.StringVar(&q.Country, "country", "", "country name or two-letter code")
+	fs.StringVar(&q.Language, "language", "", "broadcast language")
+	fs.StringVar(&q.Codec, "codec", "", "stream codec, e.g. mp3 or aac")
+	order := fs.String("order", "votes", "rank by votes or clicks")
+	fs.IntVar(&q.Limit, "limit", 20, "maximum number of results")
+	directory := fs.String("directory", "", "directory API base URL (default from config.json)")
+	save := fs.Bool("save", false, "add the results to the library")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	switch *order {
+	case "votes":
+		q.Order = OrderVotes
+	case "clicks", "clickcount":
+		q.Order = OrderClicks
+	default:
+		fmt.Fprintf(os.Stderr, "goradio: unknown order %q (want votes or clicks)\n", *order)
+		return 2
+	}
+
+	if *directory == "" {
+		*directory = LoadConfig().DirectoryURL
+	}
+	results, err := NewDirectoryClient(*directory).Search(q)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "goradio: %v\n", err)
+		return 1
+	}
+
+	var stations []RadioStation
+	for _, r := range results {
+		fmt.Println(formatDirectoryResult(r))
+		stations = append(stations, r.Station())
+	}
+
+	if *save {
+		result, err := ImportStations(stations)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "goradio: %v\n", err)
+			return 1
+		}
+		fmt.Fprintln(os.Stderr, result)
+	}
+	return 0
+}
+
+// runSync implements "goradio sync [-url URL]"
+func runSync(args []string) int {
+	fs := flag.NewFlagSet("sync", flag.ContinueOnError)
+	catalogURL := fs.String("url", "", "SomaFM catalog URL (default from config.json)")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if *catalogURL == "" {
+		*catalogURL = LoadConfig().SomaFMURL
+	}
+
+	result := SyncSomaFM(*catalogURL)
+	fmt.Println(result)
+	if result.Offline {
+		return 1
+	}
+	return 0
+}
+
+// runCheck implements "goradio check [-json] [-strict] [-parallel N] [-timeout D] [-tags FILTER]".
+// It exits 1 when a station is dead (or degraded, with -strict) so CI can catch it.
+func runCheck(args []string) int {
+	fs := flag.NewFlagSet("check", flag.ContinueOnError)
+	asJSON := fs.Bool("json", false, "print the full report as JSON")
+	strict := fs.Bool("strict", false, "fail on degraded stations too")
+	parallel := fs.Int("parallel", defaultCheckParallel, "stations probed at once")
+	timeout := fs.Duration("timeout", defaultCheckTimeout, "time allowed per station")
+	tags := fs.String("tags", "", "only check stations matching a tag filter")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	stations, err := LoadStations()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "goradio: %v\n", err)
+		return 1
+	}
+	stations = ParseTagFilter(*tags).Apply(stations)
+
+	results := CheckStations(stations, *parallel, *timeout)
+	summary := SummarizeHealth(results)
+
+	if *asJSON {
+		data, err := json.MarshalIndent(struct {
+			Summary  HealthSummary  `json:"summary"`
+			Stations []HealthResult `json:"stations"`
+		}{summary, results}, "", "  ")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "goradio: %v\n", err)
+			return 1
+		}
+		fmt.Println(string(data))
+	} else {
+		for _, r := range results {
+			line := fmt.Sprintf("%-8s %s", r.Status, r.Name)
+			if problem := r.Problem(); problem != "" {
+				line += ": " + firstLine(problem)
+			}
+			fmt.Println(line)
+		}
+		fmt.Println(summary)
+	}
+
+	if summary.Dead > 0 || (*strict && summary.Degraded > 0) {
+		return 1
+	}
+	return 0
+}
+
+// runDupes implements "goradio dupes [-offline] [-parallel N] [-timeout D]".
+// It exits 1 when duplicates are found; merging them is done in the TUI with D.
+func runDupes(args []string) int {
+	fs := flag.NewFlagSet("dupes", flag.ContinueOnError)
+	offline := fs.Bool("offline", false, "only compare URLs; do not resolve playlists or read ICY names")
+	parallel := fs.Int("parallel", defaultCheckParallel, "stations probed at once")
+	timeout := fs.Duration("timeout", defaultCheckTimeout, "time allowed per station")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	stations, err := LoadStations()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "goradio: %v\n", err)
+		return 1
+	}
+	var ids map[string]StreamIdentity
+	if !*offline {
+		ids = IdentifyStreams(stations, *parallel, *timeout)
+	}
+
+	groups := FindDuplicates(stations, ids)
+	for _, g := range groups {
+		fmt.Printf("%s:\n", strings.Join(g.Reasons, ", "))
+		for _, s := range g.Stations {
+			fmt.Printf("  %-20s %s\n", s.Key(), s.URL)
+		}
+	}
+	fmt.Printf("%d duplicate groups\n", len(groups))
+	if len(groups) > 0 {
+		return 1
+	}
+	return 0
+}
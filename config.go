@@ -0,0 +1,137 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Config holds user preferences that persist across restarts
+type Config struct {
+	// Quality is the global stream quality preference
+	Quality Quality `json:"quality"`
+	// StationQuality overrides the global preference for individual stations, keyed by station key
+	StationQuality map[string]Quality `json:"station_quality,omitempty"`
+
+	// WarmMode pre-opens the highlighted station for near-instant switching
+	WarmMode bool `json:"warm_mode"`
+	// MaxWarm caps concurrent warm connections, including ones still connecting
+	MaxWarm int `json:"max_warm"`
+	// WarmCacheKB is the read-ahead cache of each warm connection. It is a size,
+	// not a rate: a paused connection stops downloading once its cache is full.
+	WarmCacheKB int `json:"warm_cache_kb"`
+
+	// AudioDevice is the mpv output device used by default; empty means mpv's default
+	AudioDevice string `json:"audio_device,omitempty"`
+	// StationDevices overrides the output device for individual stations, keyed by station key
+	StationDevices map[string]string `json:"station_devices,omitempty"`
+
+	// Visualizer shows a live spectrum (needs ffmpeg) instead of the wave animation
+	Visualizer bool `json:"visualizer"`
+
+	// DirectoryURL is the Radio Browser compatible API used by the directory browser
+	DirectoryURL string `json:"directory_url,omitempty"`
+
+	// SomaFMURL is the SomaFM channel catalog; empty means the official one
+	SomaFMURL string `json:"somafm_url,omitempty"`
+	// SomaFMRefreshHours is how often the catalog is refreshed while running; 0 means only on demand
+	SomaFMRefreshHours int `json:"somafm_refresh_hours"`
+
+	// MonitorMinutes is how often favorites and recently played stations are checked in the background; 0 disables it
+	MonitorMinutes int `json:"monitor_minutes"`
+
+	// Artwork is how station logos are drawn: auto, halfblock, kitty, sixel or off
+	Artwork ArtworkMode `json:"artwork,omitempty"`
+
+	// Sort is the order the station list is shown in
+	Sort StationSort `json:"sort,omitempty"`
+}
+
+// configPath returns the location of the preferences file
+func configPath() (string, error) {
+	dir, err := ConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "config.json"), nil
+}
+
+// LoadConfig reads the preferences file, returning defaults if it does not exist
+func LoadConfig() *Config {
+	cfg := &Config{
+		Quality:        QualityHigh,
+		StationQuality: make(map[string]Quality),
+		StationDevices: make(map[string]string),
+		MaxWarm:        defaultMaxWarm,
+		WarmCacheKB:    defaultWarmCacheKB,
+		Visualizer:     true,
+
+		SomaFMRefreshHours: int(defaultSomaFMRefresh / time.Hour),
+		MonitorMinutes:     int(defaultMonitorInterval / time.Minute),
+		Sort:               SortCustom,
+		Artwork:            ArtworkAuto,
+	}
+
+	path, err := configPath()
+	if err != nil {
+		log.Printf("config: %v", err)
+		return cfg
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Printf("config: reading %s: %v", path, err)
+		}
+		return cfg
+	}
+
+	if err := json.Unmarshal(data, cfg); err != nil {
+		log.Printf("config: parsing %s: %v", path, err)
+	}
+	if cfg.StationQuality == nil {
+		cfg.StationQuality = make(map[string]Quality)
+	}
+	if cfg.StationDevices == nil {
+		cfg.StationDevices = make(map[string]string)
+	}
+	return cfg
+}
+
+// Save writes the preferences file
+func (c *Config) Save() error {
+	path, err := configPath()
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(path, append(data, '\n'), 0644)
+}
+
+// QualityFor returns the effective quality for a station and whether it is an override
+func (c *Config) QualityFor(station *RadioStation) (Quality, bool) {
+	if q, ok := c.StationQuality[station.Key()]; ok {
+		return q, true
+	}
+	return c.Quality, false
+}
+
+// DeviceFor returns the effective audio device for a station and whether it is an override
+func (c *Config) DeviceFor(station *RadioStation) (string, bool) {
+	if d, ok := c.StationDevices[station.Key()]; ok {
+		return d, true
+	}
+	return c.AudioDevice, false
+}
+
+// PlayOptionsFor gathers the playback preferences that apply to a station
+func (c *Config) PlayOptionsFor(station *RadioStation) PlayOptions {
+	quality, _ := c.QualityFor(station)
+	device, _ := c.DeviceFor(station)
+	return PlayOptions{Quality: quality, AudioDevice: device, Visualizer: c.Visualizer, Settings: station.Settings}
+}
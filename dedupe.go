@@ -0,0 +1,541 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// genericICYNames are server defaults that say nothing about the station
+var genericICYNames = map[string]bool{
+	"no name":          true,
+	"unspecified name": true,
+	"stream":           true,
+	"radio":            true,
+	"icecast":          true,
+	"shoutcast server": true,
+}
+
+// CanonicalURL reduces a stream URL to the form two copies of the same stream
+// share: the scheme, default ports, trailing slashes and a playlist extension
+// are dropped, the host is lowercased and numbered Icecast relays (ice1, ice2...)
+// collapse into one. The result is for comparing, not for playing.
+func CanonicalURL(raw string) string {
+	raw = strings.TrimSpace(raw)
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return strings.ToLower(raw)
+	}
+
+	host := strings.ToLower(u.Hostname())
+	if label, rest, ok := strings.Cut(host, "."); ok && isRelayLabel(label) {
+		host = "ice." + rest
+	}
+	if port := u.Port(); port != "" && port != "80" && port != "443" {
+		host += ":" + port
+	}
+
+	// Shoutcast v1 servers are often addressed as "/;" to get the raw stream
+	p := strings.TrimRight(u.Path, "/;")
+	switch strings.ToLower(path.Ext(p)) {
+	case ".pls", ".m3u", ".m3u8":
+		p = strings.TrimSuffix(p, path.Ext(p))
+	}
+
+	canonical := host + p
+	if u.RawQuery != "" {
+		canonical += "?" + u.Query().Encode()
+	}
+	return canonical
+}
+
+// isRelayLabel reports whether a host label is a numbered relay such as "ice2"
+func isRelayLabel(label string) bool {
+	digits := strings.TrimPrefix(label, "ice")
+	if digits == label || digits == "" {
+		return false
+	}
+	return strings.Trim(digits, "0123456789") == ""
+}
+
+// StreamIdentity is what the server says about a station when it is played
+type StreamIdentity struct {
+	StreamURL string // Where the audio came from, after playlists
+	ICYName   string
+}
+
+// IdentifyStreams connects to every station to learn where its playlist leads and
+// what the server calls it. Stations that cannot be reached are left out.
+func IdentifyStreams(stations []RadioStation, parallel int, timeout time.Duration) map[string]StreamIdentity {
+	ids := make(map[string]StreamIdentity)
+	for _, r := range CheckStations(stations, parallel, timeout) {
+		if r.Connected {
+			ids[r.Key] = StreamIdentity{StreamURL: r.StreamURL, ICYName: r.ICYName}
+		}
+	}
+	return ids
+}
+
+// DuplicateGroup is a set of library stations that play the same stream
+type DuplicateGroup struct {
+	Stations []RadioStation
+	Reasons  []string // Why they were matched, e.g. "same URL"
+}
+
+// FindDuplicates groups stations whose URLs or stream variants are the same
+// once canonicalized. With ids, stations whose playlists lead to the same
+// stream are grouped too, and so are stations whose servers announce the same
+// ICY name when they also share a stream host or a name: many unrelated
+// stations run with their software's default name.
+func FindDuplicates(stations []RadioStation, ids map[string]StreamIdentity) []DuplicateGroup {
+	parent := make([]int, len(stations))
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+
+	type link struct {
+		a, b   int
+		reason string
+	}
+	var links []link
+	seen := make(map[string]int)
+	match := func(i int, key, reason string) {
+		if j, ok := seen[key]; ok {
+			if j != i {
+				links = append(links, link{j, i, reason})
+				parent[find(i)] = find(j)
+			}
+			return
+		}
+		seen[key] = i
+	}
+
+	for i, s := range stations {
+		match(i, "url:"+CanonicalURL(s.URL), "same URL")
+		for _, v := range s.Streams {
+			match(i, "url:"+CanonicalURL(v.URL), "same URL")
+		}
+	}
+	byICYName := make(map[string][]int)
+	for i, s := range stations {
+		id, ok := ids[s.Key()]
+		if !ok {
+			continue
+		}
+		if id.StreamURL != "" {
+			match(i, "url:"+CanonicalURL(id.StreamURL), "same stream after playlist")
+		}
+		if name := strings.ToLower(strings.TrimSpace(id.ICYName)); name != "" && !genericICYNames[name] {
+			byICYName[name] = append(byICYName[name], i)
+		}
+	}
+	for _, group := range byICYName {
+		for x, i := range group {
+			for _, j := range group[x+1:] {
+				if find(i) != find(j) && corroborated(stations[i], stations[j], ids) {
+					links = append(links, link{i, j, "same ICY name"})
+					parent[find(j)] = find(i)
+				}
+			}
+		}
+	}
+
+	index := make(map[int]int)
+	var groups []DuplicateGroup
+	for i := range stations {
+		root := find(i)
+		g, ok := index[root]
+		if !ok {
+			g = len(groups)
+			index[root] = g
+			groups = append(groups, DuplicateGroup{})
+		}
+		groups[g].Stations = append(groups[g].Stations, stations[i])
+	}
+	for _, l := range links {
+		g := &groups[index[find(l.a)]]
+		if !containsString(g.Reasons, l.reason) {
+			g.Reasons = append(g.Reasons, l.reason)
+		}
+	}
+
+	var duplicates []DuplicateGroup
+	for _, g := range groups {
+		if len(g.Stations) > 1 {
+			duplicates = append(duplicates, g)
+		}
+	}
+	return duplicates
+}
+
+// corroborated reports whether two stations with the same ICY name also share
+// a stream host or a name, which makes the ICY name worth trusting
+func corroborated(a, b RadioStation, ids map[string]StreamIdentity) bool {
+	if slugify(a.Name) != "" && slugify(a.Name) == slugify(b.Name) {
+		return true
+	}
+	hostA, hostB := streamHost(a, ids), streamHost(b, ids)
+	return hostA != "" && hostA == hostB
+}
+
+// streamHost returns the canonical host a station streams from, after playlists
+func streamHost(s RadioStation, ids map[string]StreamIdentity) string {
+	streamURL := s.URL
+	if id, ok := ids[s.Key()]; ok && id.StreamURL != "" {
+		streamURL = id.StreamURL
+	}
+	host, _, _ := strings.Cut(CanonicalURL(streamURL), "/")
+	return host
+}
+
+// containsString reports whether list holds s
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// richness scores how much a station tells about itself; merging keeps the richest
+func richness(s RadioStation) int {
+	n := len(s.AllTags()) + len(s.Streams) + len(s.Mirrors)
+	for _, field := range []string{s.Description, s.Logo, s.Homepage} {
+		if field != "" {
+			n++
+		}
+	}
+	return n
+}
+
+// Richest returns the index of the station with the most metadata, the first on ties
+func (g DuplicateGroup) Richest() int {
+	best := 0
+	for i, s := range g.Stations {
+		if richness(s) > richness(g.Stations[best]) {
+			best = i
+		}
+	}
+	return best
+}
+
+// MergeStation fills in what keep lacks from others: a missing genre, logo,
+// homepage or playback settings, the longest description, and the union of
+// tags, stream variants and mirrors. Keep's key, name and URL are left alone.
+// The others' own URLs are kept too: as mirrors when only the host differs
+// from keep's URL, as stream variants otherwise. Since variants are played in
+// preference to the URL, keep's URL then becomes the first variant.
+func MergeStation(keep RadioStation, others ...RadioStation) RadioStation {
+	merged := keep
+	tags := keep.AllTags()
+	streams := append([]StreamVariant(nil), keep.Streams...)
+	mirrors := append([]string(nil), keep.Mirrors...)
+
+	haveStream := map[string]bool{CanonicalURL(keep.URL): true}
+	for _, v := range streams {
+		haveStream[CanonicalURL(v.URL)] = true
+	}
+	addStream := func(v StreamVariant) {
+		if !haveStream[CanonicalURL(v.URL)] {
+			haveStream[CanonicalURL(v.URL)] = true
+			streams = append(streams, v)
+		}
+	}
+	for _, other := range others {
+		if merged.Genre == "" {
+			merged.Genre = other.Genre
+		}
+		if merged.Settings == nil {
+			merged.Settings = other.Settings
+		}
+		if merged.Logo == "" {
+			merged.Logo = other.Logo
+		}
+		if merged.Homepage == "" {
+			merged.Homepage = other.Homepage
+		}
+		if len(other.Description) > len(merged.Description) {
+			merged.Description = other.Description
+		}
+		tags = append(tags, other.AllTags()...)
+		for _, v := range other.Streams {
+			addStream(v)
+		}
+		if host, ok := mirrorHost(keep.URL, other.URL); ok {
+			other.Mirrors = append([]string{host}, other.Mirrors...)
+		} else if other.URL != "" {
+			addStream(StreamVariant{URL: other.URL})
+		}
+		for _, host := range other.Mirrors {
+			if !containsString(mirrors, host) {
+				mirrors = append(mirrors, host)
+			}
+		}
+	}
+	if len(keep.Streams) == 0 && len(streams) > 0 {
+		streams = append([]StreamVariant{{URL: keep.URL}}, streams...)
+	}
+
+	merged.Tags = nil
+	for _, tag := range NormalizeTags(tags) {
+		if tagKey(tag) != tagKey(merged.Genre) {
+			merged.Tags = append(merged.Tags, tag)
+		}
+	}
+	merged.Streams = streams
+	merged.Mirrors = mirrors
+	return merged
+}
+
+// mirrorHost returns the host of other when it serves the same path as base
+// from a different server, which is what a mirror is
+func mirrorHost(base, other string) (string, bool) {
+	b, err := url.Parse(base)
+	if err != nil || b.Host == "" {
+		return "", false
+	}
+	o, err := url.Parse(other)
+	if err != nil || o.Host == "" {
+		return "", false
+	}
+	if strings.EqualFold(b.Host, o.Host) || b.Path != o.Path || b.RawQuery != o.RawQuery {
+		return "", false
+	}
+	return o.Host, true
+}
+
+// MergeDuplicates saves merged under keepKey and removes the stations in
+// dropKeys, all in one write of the station file
+func MergeDuplicates(keepKey string, merged RadioStation, dropKeys []string) error {
+	path, file, err := readStationFileForEdit()
+	if err != nil {
+		return err
+	}
+
+	merged.ID = keepKey
+	kept := file.Stations[:0]
+	replaced := false
+	for _, s := range file.Stations {
+		switch {
+		case containsString(dropKeys, s.Key()):
+			continue
+		case s.Key() == keepKey:
+			s = merged
+			replaced = true
+		}
+		kept = append(kept, s)
+	}
+	file.Stations = kept
+	if !replaced {
+		// A built-in station gets a full override entry
+		file.Stations = append(file.Stations, merged)
+	}
+
+	for _, key := range dropKeys {
+		if isBuiltinStation(key) && !containsString(file.Remove, key) {
+			file.Remove = append(file.Remove, key)
+		}
+	}
+	return writeStationFile(path, file)
+}
+
+// dedupeView is the TUI for reviewing and merging duplicate stations
+type dedupeView struct {
+	loading bool
+	groups  []DuplicateGroup
+	group   int // Group being reviewed
+	keep    int // Station in the group that the others merge into
+	err     string
+}
+
+// duplicatesMsg delivers the duplicate groups found in the library
+type duplicatesMsg []DuplicateGroup
+
+// findDuplicatesCmd resolves every station's stream and looks for duplicates in the background
+func findDuplicatesCmd(stations []RadioStation) tea.Cmd {
+	return func() tea.Msg {
+		ids := IdentifyStreams(stations, defaultCheckParallel, defaultCheckTimeout)
+		return duplicatesMsg(FindDuplicates(stations, ids))
+	}
+}
+
+// RenderDedupeView renders the current duplicate group and what merging it would keep
+func RenderDedupeView(d *dedupeView) string {
+	muted := lipgloss.NewStyle().Foreground(mutedColor)
+	content := []string{titleStyle.Render("Duplicate Stations")}
+	switch {
+	case d.loading:
+		return strings.Join(append(content, "Resolving playlists and ICY names..."), "\n")
+	case len(d.groups) == 0:
+		return strings.Join(append(content, "No duplicates found", "", muted.Render("Esc  Close")), "\n")
+	}
+
+	g := d.groups[d.group]
+	content = append(content,
+		fmt.Sprintf("Group %d of %d: %s", d.group+1, len(d.groups), strings.Join(g.Reasons, ", ")),
+		"")
+	for i, s := range g.Stations {
+		line := fmt.Sprintf("  %s (%s)", truncate(s.Name, 30), s.Genre)
+		if i == d.keep {
+			line = selectedItemStyle.Render("▸ " + truncate(s.Name, 30) + " (" + s.Genre + ")  keep")
+		} else {
+			line = normalItemStyle.Render(line)
+		}
+		content = append(content, line, muted.Render("    "+truncate(s.URL, 48)))
+	}
+
+	merged := d.merged()
+	content = append(content, "", "Merged:",
+		"  Genre: "+merged.Genre,
+		"  Tags: "+strings.Join(merged.Tags, ", "),
+		fmt.Sprintf("  Streams: %d   Mirrors: %d", len(merged.Streams), len(merged.Mirrors)),
+		"  "+truncate(merged.Description, 48))
+	if d.err != "" {
+		content = append(content, "", lipgloss.NewStyle().Foreground(accentColor).Render(d.err))
+	}
+
+	content = append(content, "", muted.Render(strings.Join([]string{
+		"↑/↓    Previous/next group",
+		"←/→    Choose the station to keep",
+		"Enter  Merge the group",
+		"Esc    Close",
+	}, "\n")))
+	return strings.Join(content, "\n")
+}
+
+// merged previews the current group merged into the chosen station
+func (d *dedupeView) merged() RadioStation {
+	g := d.groups[d.group]
+	var others []RadioStation
+	for i, s := range g.Stations {
+		if i != d.keep {
+			others = append(others, s)
+		}
+	}
+	return MergeStation(g.Stations[d.keep], others...)
+}
+
+// selectGroup moves to group i and proposes its richest station as the one to keep
+func (d *dedupeView) selectGroup(i int) {
+	d.group = i
+	d.keep = d.groups[i].Richest()
+	d.err = ""
+}
+
+// startDedupe opens the duplicates view and starts looking through the whole library
+func (m Model) startDedupe() (tea.Model, tea.Cmd) {
+	m.dedupe = &dedupeView{loading: true}
+	return m, findDuplicatesCmd(m.stations)
+}
+
+// handleDuplicates shows the groups found if the view is still open
+func (m Model) handleDuplicates(groups []DuplicateGroup) (tea.Model, tea.Cmd) {
+	if m.dedupe == nil {
+		return m, nil
+	}
+	m.dedupe.loading = false
+	m.dedupe.groups = groups
+	if len(groups) > 0 {
+		m.dedupe.selectGroup(0)
+	}
+	return m, nil
+}
+
+// updateDedupe handles keys while the duplicates view is open
+func (m Model) updateDedupe(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	d := m.dedupe
+	if msg.String() == "esc" || msg.String() == "D" || msg.String() == "q" {
+		m.dedupe = nil
+		return m, nil
+	}
+	if d.loading || len(d.groups) == 0 {
+		return m, nil
+	}
+
+	switch msg.String() {
+	case "up", "k":
+		if d.group > 0 {
+			d.selectGroup(d.group - 1)
+		}
+	case "down", "j":
+		if d.group < len(d.groups)-1 {
+			d.selectGroup(d.group + 1)
+		}
+	case "left", "h":
+		d.keep = (d.keep + len(d.groups[d.group].Stations) - 1) % len(d.groups[d.group].Stations)
+	case "right", "l", "tab":
+		d.keep = (d.keep + 1) % len(d.groups[d.group].Stations)
+	case "enter":
+		return m.mergeDuplicateGroup()
+	}
+	return m, nil
+}
+
+// mergeDuplicateGroup merges the reviewed group, carrying favorites and
+// ratings of the dropped stations over to the one kept
+func (m Model) mergeDuplicateGroup() (tea.Model, tea.Cmd) {
+	d := m.dedupe
+	g := d.groups[d.group]
+	keep := g.Stations[d.keep]
+	merged := d.merged()
+
+	var drop []string
+	for i, s := range g.Stations {
+		if i != d.keep {
+			drop = append(drop, s.Key())
+		}
+	}
+	if err := MergeDuplicates(keep.Key(), merged, drop); err != nil {
+		d.err = firstLine(err.Error())
+		return m, nil
+	}
+
+	for _, key := range drop {
+		if m.favorites.Has(key) {
+			if !m.favorites.Replace(key, keep.Key()) {
+				m.favorites.Toggle(key)
+			}
+		}
+		if rating, ok := m.ratings[key]; ok {
+			if _, rated := m.ratings[keep.Key()]; !rated {
+				m.ratings[keep.Key()] = rating
+			}
+			delete(m.ratings, key)
+		}
+	}
+	m.saveFavorites()
+	if err := m.ratings.Save(); err != nil {
+		m.statusMsg = "⚠ saving ratings: " + firstLine(err.Error())
+	}
+	profilesChanged := false
+	for _, key := range drop {
+		profilesChanged = m.profiles.ReplaceKey(key, keep.Key()) || profilesChanged
+	}
+	if profilesChanged {
+		m.saveProfiles()
+	}
+
+	m.reloadStations(keep.Key())
+	m.statusMsg = fmt.Sprintf("Merged %d duplicates into %q", len(drop), merged.Name)
+
+	d.groups = append(d.groups[:d.group], d.groups[d.group+1:]...)
+	if len(d.groups) == 0 {
+		m.dedupe = nil
+		return m, nil
+	}
+	d.selectGroup(min(d.group, len(d.groups)-1))
+	return m, nil
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// AudioDevice is an output device as reported by mpv's audio-device-list
+type AudioDevice struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+// ListAudioDevices asks mpv for the available output devices. The running
+// backend is queried when there is one, otherwise a short-lived idle mpv is used.
+func (p *Player) ListAudioDevices() ([]AudioDevice, error) {
+	var devices []AudioDevice
+
+	p.mu.Lock()
+	running := p.proc
+	p.mu.Unlock()
+	if running != nil && running.conn != nil && !running.exited() {
+		if err := running.getProperty("audio-device-list", &devices); err == nil {
+			return devices, nil
+		}
+	}
+
+	proc, err := startMPVProcess("", "--idle=yes")
+	if err != nil {
+		return nil, err
+	}
+	defer proc.kill()
+
+	if err := proc.connect(5 * time.Second); err != nil {
+		return nil, err
+	}
+	if err := proc.getProperty("audio-device-list", &devices); err != nil {
+		return nil, fmt.Errorf("querying audio devices: %v", err)
+	}
+	return devices, nil
+}
+
+// SetAudioDevice switches the output device, applying it to the running stream immediately
+func (p *Player) SetAudioDevice(device string) error {
+	p.mu.Lock()
+	p.opts.AudioDevice = device
+	proc := p.proc
+	p.mu.Unlock()
+	if proc == nil || proc.conn == nil {
+		return nil
+	}
+	if device == "" {
+		device = "auto"
+	}
+	return proc.setProperty("audio-device", device)
+}
+
+// devicePicker is the state of the output device selection overlay
+type devicePicker struct {
+	devices []AudioDevice
+	cursor  int
+	loading bool
+	err     error
+}
+
+// RenderDevicePicker renders the output device list with the current choices marked
+func RenderDevicePicker(picker *devicePicker, global string, station *RadioStation, override string, hasOverride bool) string {
+	content := []string{titleStyle.Render("Audio Output")}
+
+	switch {
+	case picker.loading:
+		content = append(content, "Querying mpv for devices...")
+	case picker.err != nil:
+		content = append(content, fmt.Sprintf("Could not list devices: %v", picker.err))
+	default:
+		for i, d := range picker.devices {
+			marker := "  "
+			if d.Name == global || (global == "" && d.Name == "auto") {
+				marker = "● "
+			}
+			if hasOverride && d.Name == override {
+				marker = "★ "
+			}
+			line := fmt.Sprintf("%s%s", marker, d.Description)
+			if d.Description == "" {
+				line = marker + d.Name
+			}
+			if i == picker.cursor {
+				line = selectedItemStyle.Render(line)
+			} else {
+				line = normalItemStyle.Render(line)
+			}
+			content = append(content, line)
+		}
+	}
+
+	stationName := "-"
+	if station != nil {
+		stationName = station.Name
+	}
+	content = append(content, "",
+		lipgloss.NewStyle().Foreground(mutedColor).Render(strings.Join([]string{
+			"● default   ★ override for " + stationName,
+			"Enter  use as default",
+			"s      use for this station only",
+			"c      clear station override",
+			"Esc    close",
+		}, "\n")))
+
+	return strings.Join(content, "\n")
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// StreamStats holds network and buffer health for the current stream
+type StreamStats struct {
+	CacheDuration    float64       // Seconds of audio buffered (demuxer-cache-duration)
+	Throughput       float64       // Download speed in bytes per second (cache-speed)
+	Reconnects       int           // Times the backend was restarted after the stream died
+	TimeToFirstAudio time.Duration // Time from Play until mpv reported playback start
+	ResolvedURL      string        // Final stream URL after HTTP redirects
+	RequestedAt      time.Time     // When playback was requested
+	StreamURL        string        // The stream mpv is playing
+	Info             *StreamInfo   // What the server says about the stream, as mpv reports it
+}
+
+// bufferTarget is the buffer level shown as a full bar in the diagnostics panel
+const bufferTarget = 10.0
+
+// resolveFinalURL follows HTTP redirects and returns the URL that actually serves the stream.
+// It only asks for the headers, the way the station's settings say mpv requests the stream.
+func resolveFinalURL(url string, settings *StreamSettings) (string, error) {
+	req, err := http.NewRequest("HEAD", url, nil)
+	if err != nil {
+		return "", err
+	}
+	settings.setHeaders(req)
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	resp.Body.Close()
+	// A server that refuses HEAD has still been reached through every redirect
+	return resp.Request.URL.String(), nil
+}
+
+// logStreamStats writes the current diagnostics to the log for later analysis
+func logStreamStats(station *RadioStation, stats StreamStats) {
+	name := ""
+	if station != nil {
+		name = station.Name
+	}
+	log.Printf("diag station=%q cache=%.2fs throughput=%.0fB/s reconnects=%d ttfa=%s url=%s",
+		name, stats.CacheDuration, stats.Throughput, stats.Reconnects,
+		stats.TimeToFirstAudio.Round(time.Millisecond), stats.ResolvedURL)
+}
+
+// formatThroughput renders a byte rate in human readable units
+func formatThroughput(bytesPerSec float64) string {
+	switch {
+	case bytesPerSec >= 1024*1024:
+		return fmt.Sprintf("%.1f MB/s", bytesPerSec/(1024*1024))
+	case bytesPerSec >= 1024:
+		return fmt.Sprintf("%.1f KB/s", bytesPerSec/1024)
+	default:
+		return fmt.Sprintf("%.0f B/s", bytesPerSec)
+	}
+}
+
+// bufferBar draws a fill gauge for the buffered duration
+func bufferBar(seconds float64, width int) string {
+	filled := int(seconds / bufferTarget * float64(width))
+	if filled > width {
+		filled = width
+	}
+	if filled < 0 {
+		filled = 0
+	}
+	return strings.Repeat("█", filled) + strings.Repeat("░", width-filled)
+}
+
+// RenderDiagnostics renders the network and buffer health panel
+func RenderDiagnostics(stats StreamStats, state PlayerState) string {
+	ttfa := "waiting..."
+	if stats.TimeToFirstAudio > 0 {
+		ttfa = stats.TimeToFirstAudio.Round(time.Millisecond).String()
+	} else if state != StatePlaying && state != StateLoading {
+		ttfa = "-"
+	}
+
+	resolved := stats.ResolvedURL
+	if resolved == "" {
+		resolved = "resolving..."
+	}
+
+	content := []string{
+		titleStyle.Render("Diagnostics"),
+		fmt.Sprintf("Buffer:      %s %.1fs", bufferBar(stats.CacheDuration, 20), stats.CacheDuration),
+		fmt.Sprintf("Throughput:  %s", formatThroughput(stats.Throughput)),
+		fmt.Sprintf("Reconnects:  %d", stats.Reconnects),
+		fmt.Sprintf("First audio: %s", ttfa),
+		"",
+		"Resolved URL:",
+		resolved,
+	}
+
+	return strings.Join(content, "\n")
+}
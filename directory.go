@@ -0,0 +1,220 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultDirectoryURL is the Radio Browser round-robin endpoint; any mirror
+// or compatible server can be set as directory_url in config.json
+const defaultDirectoryURL = "https://all.api.radio-browser.info"
+
+// directoryTimeout bounds a single directory request
+const directoryTimeout = 10 * time.Second
+
+// defaultDirectoryLimit is how many results a search returns unless asked otherwise
+const defaultDirectoryLimit = 50
+
+// DirectoryOrder is the field search results are ranked by, most popular first
+type DirectoryOrder string
+
+const (
+	OrderVotes  DirectoryOrder = "votes"
+	OrderClicks DirectoryOrder = "clickcount"
+)
+
+// Next cycles between the supported orderings
+func (o DirectoryOrder) Next() DirectoryOrder {
+	if o == OrderVotes {
+		return OrderClicks
+	}
+	return OrderVotes
+}
+
+func (o DirectoryOrder) String() string {
+	if o == OrderClicks {
+		return "clicks"
+	}
+	return "votes"
+}
+
+// DirectoryQuery describes a directory search; empty fields are not filtered on
+type DirectoryQuery struct {
+	Name     string
+	Tag      string
+	Country  string // Full name, or a two-letter country code
+	Language string
+	Codec    string
+	Order    DirectoryOrder
+	Limit    int
+}
+
+// DirectoryStation is a station as the Radio Browser API describes it
+type DirectoryStation struct {
+	UUID        string `json:"stationuuid"`
+	Name        string `json:"name"`
+	URL         string `json:"url"`
+	ResolvedURL string `json:"url_resolved"`
+	Homepage    string `json:"homepage"`
+	Favicon     string `json:"favicon"`
+	Tags        string `json:"tags"`
+	Country     string `json:"country"`
+	CountryCode string `json:"countrycode"`
+	Language    string `json:"language"`
+	Codec       string `json:"codec"`
+	Bitrate     int    `json:"bitrate"`
+	Votes       int    `json:"votes"`
+	ClickCount  int    `json:"clickcount"`
+}
+
+// Station converts a directory entry into a library station
+func (d DirectoryStation) Station() RadioStation {
+	streamURL := strings.TrimSpace(d.ResolvedURL)
+	if streamURL == "" {
+		streamURL = strings.TrimSpace(d.URL)
+	}
+
+	station := RadioStation{
+		Name: strings.TrimSpace(d.Name),
+		URL:  streamURL,
+	}
+	if favicon := strings.TrimSpace(d.Favicon); checkStreamURL(favicon) == "" {
+		station.Logo = favicon
+	}
+	if tags := NormalizeTags(d.tagList()); len(tags) > 0 {
+		station.Genre, station.Tags = tags[0], tags[1:]
+	}
+
+	if homepage := strings.TrimSpace(d.Homepage); checkStreamURL(homepage) == "" {
+		station.Homepage = homepage
+	}
+
+	var about []string
+	for _, part := range []string{d.Country, d.Language} {
+		if part = strings.TrimSpace(part); part != "" {
+			about = append(about, part)
+		}
+	}
+	station.Description = strings.Join(about, " · ")
+
+	if codec := directoryCodec(d.Codec); codec != "" || d.Bitrate > 0 {
+		station.Streams = []StreamVariant{{URL: streamURL, Codec: codec, Bitrate: d.Bitrate}}
+	}
+	return station
+}
+
+// tagList splits the comma separated tags field
+func (d DirectoryStation) tagList() []string {
+	var tags []string
+	for _, tag := range strings.Split(d.Tags, ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// directoryCodec maps Radio Browser codec names ("MP3", "AAC+") onto ours ("mp3", "aacp")
+func directoryCodec(codec string) string {
+	codec = strings.ToLower(strings.TrimSpace(codec))
+	if codec == "unknown" {
+		return ""
+	}
+	return strings.ReplaceAll(codec, "+", "p")
+}
+
+// titleCase capitalizes the first letter of each word
+func titleCase(s string) string {
+	words := strings.Fields(s)
+	for i, w := range words {
+		r := []rune(w)
+		words[i] = strings.ToUpper(string(r[0])) + string(r[1:])
+	}
+	return strings.Join(words, " ")
+}
+
+// DirectoryClient talks to a Radio Browser compatible API
+type DirectoryClient struct {
+	BaseURL string
+	HTTP    *http.Client
+}
+
+// NewDirectoryClient creates a client for baseURL, or the public directory when empty
+func NewDirectoryClient(baseURL string) *DirectoryClient {
+	if baseURL == "" {
+		baseURL = defaultDirectoryURL
+	}
+	return &DirectoryClient{
+		BaseURL: strings.TrimRight(baseURL, "/"),
+		HTTP:    &http.Client{Timeout: directoryTimeout},
+	}
+}
+
+// Search returns stations matching the query, most popular first. Stations
+// the directory's own checker marks as broken are left out.
+func (c *DirectoryClient) Search(q DirectoryQuery) ([]DirectoryStation, error) {
+	params := url.Values{}
+	set := func(key, value string) {
+		if value = strings.TrimSpace(value); value != "" {
+			params.Set(key, value)
+		}
+	}
+	set("name", q.Name)
+	set("tag", q.Tag)
+	if country := strings.TrimSpace(q.Country); len(country) == 2 {
+		set("countrycode", strings.ToUpper(country))
+	} else {
+		set("country", country)
+	}
+	set("language", q.Language)
+	set("codec", q.Codec)
+
+	order := q.Order
+	if order == "" {
+		order = OrderVotes
+	}
+	limit := q.Limit
+	if limit <= 0 {
+		limit = defaultDirectoryLimit
+	}
+	params.Set("order", string(order))
+	params.Set("reverse", "true")
+	params.Set("hidebroken", "true")
+	params.Set("limit", strconv.Itoa(limit))
+
+	var results []DirectoryStation
+	if err := c.get("/json/stations/search?"+params.Encode(), &results); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// get fetches an API path and decodes its JSON answer into v
+func (c *DirectoryClient) get(path string, v interface{}) error {
+	req, err := http.NewRequest("GET", c.BaseURL+path, nil)
+	if err != nil {
+		return err
+	}
+	// The Radio Browser maintainers ask clients to identify themselves
+	req.Header.Set("User-Agent", "goradio-hub/"+Version)
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return fmt.Errorf("directory: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("directory: %s answered %s", c.BaseURL, resp.Status)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("directory: decoding answer: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,494 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// placeholderICYValues are server defaults that describe nothing
+var placeholderICYValues = map[string]bool{
+	"-":                       true,
+	"n/a":                     true,
+	"none":                    true,
+	"unknown":                 true,
+	"unspecified":             true,
+	"unspecified description": true,
+	"default genre":           true,
+	"various":                 true,
+}
+
+// StreamInfo is what a stream server says about itself in its response headers
+type StreamInfo struct {
+	Name        string `json:"name,omitempty"`        // icy-name
+	Genre       string `json:"genre,omitempty"`       // icy-genre
+	Description string `json:"description,omitempty"` // icy-description
+	Homepage    string `json:"homepage,omitempty"`    // icy-url
+	Bitrate     int    `json:"bitrate,omitempty"`     // icy-br, else ice-audio-info; kbps
+	SampleRate  int    `json:"sample_rate,omitempty"` // ice-audio-info, Hz
+	Channels    int    `json:"channels,omitempty"`    // ice-audio-info
+	Codec       string `json:"codec,omitempty"`       // From the content type
+}
+
+// ParseStreamInfo reads the icy-* and ice-audio-info headers of a stream
+// response, returning nil when the server sent none
+func ParseStreamInfo(header http.Header) *StreamInfo {
+	found := false
+	for name := range header {
+		name = strings.ToLower(name)
+		if strings.HasPrefix(name, "icy-") || name == "ice-audio-info" {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return nil
+	}
+
+	info := &StreamInfo{
+		Name:        icyText(header.Get("Icy-Name")),
+		Genre:       icyText(header.Get("Icy-Genre")),
+		Description: icyText(header.Get("Icy-Description")),
+		Homepage:    icyHomepage(header.Get("Icy-Url")),
+		Bitrate:     leadingInt(header.Get("Icy-Br")),
+		Codec:       contentTypeCodec(header.Get("Content-Type")),
+	}
+	// ice-audio-info: "ice-samplerate=44100;ice-bitrate=128;ice-channels=2"
+	for _, pair := range strings.Split(header.Get("Ice-Audio-Info"), ";") {
+		name, value, _ := strings.Cut(strings.TrimSpace(pair), "=")
+		switch strings.TrimPrefix(strings.ToLower(name), "ice-") {
+		case "bitrate":
+			if info.Bitrate == 0 {
+				info.Bitrate = leadingInt(value)
+			}
+		case "samplerate":
+			info.SampleRate = leadingInt(value)
+		case "channels":
+			info.Channels = leadingInt(value)
+		}
+	}
+	return info
+}
+
+// icyText cleans up a header value: servers often send Latin-1 and placeholders
+func icyText(value string) string {
+	if !utf8.ValidString(value) {
+		runes := make([]rune, len(value))
+		for i := 0; i < len(value); i++ {
+			runes[i] = rune(value[i])
+		}
+		value = string(runes)
+	}
+	value = strings.TrimSpace(value)
+	if placeholderICYValues[strings.ToLower(value)] {
+		return ""
+	}
+	return value
+}
+
+// icyHomepage returns icy-url as an http(s) URL, adding the scheme servers often leave out
+func icyHomepage(value string) string {
+	value = icyText(value)
+	if value != "" && !strings.Contains(value, "://") {
+		value = "http://" + value
+	}
+	if checkStreamURL(value) != "" {
+		return ""
+	}
+	return value
+}
+
+// leadingInt parses the number at the start of a value such as "128" or "128,128"
+func leadingInt(value string) int {
+	value = strings.TrimSpace(value)
+	end := 0
+	for end < len(value) && value[end] >= '0' && value[end] <= '9' {
+		end++
+	}
+	n, _ := strconv.Atoi(value[:end])
+	return n
+}
+
+// contentTypeCodec names the codec of an audio content type, or "" when unknown
+func contentTypeCodec(contentType string) string {
+	switch strings.ToLower(strings.TrimSpace(strings.Split(contentType, ";")[0])) {
+	case "audio/mpeg", "audio/mp3", "audio/mpeg3":
+		return "mp3"
+	case "audio/aac", "audio/x-aac":
+		return "aac"
+	case "audio/aacp":
+		return "aacp"
+	case "audio/ogg", "application/ogg", "audio/vorbis":
+		return "ogg"
+	case "audio/opus":
+		return "opus"
+	case "audio/flac", "audio/x-flac":
+		return "flac"
+	}
+	return ""
+}
+
+// mpvCodec names the codec mpv decodes in the terms contentTypeCodec uses, or "" when unknown
+func mpvCodec(name string) string {
+	switch name {
+	case "mp3", "aac", "opus", "flac":
+		return name
+	case "vorbis":
+		return "ogg"
+	}
+	return ""
+}
+
+// Summary describes the stream format, e.g. "MP3 128 kbps · 44.1 kHz · stereo"
+func (info *StreamInfo) Summary() string {
+	var parts []string
+	if info.Codec != "" || info.Bitrate > 0 {
+		parts = append(parts, strings.TrimSpace(StreamVariant{Codec: info.Codec, Bitrate: info.Bitrate}.String()))
+	}
+	if info.SampleRate > 0 {
+		parts = append(parts, strconv.FormatFloat(float64(info.SampleRate)/1000, 'f', -1, 64)+" kHz")
+	}
+	switch info.Channels {
+	case 1:
+		parts = append(parts, "mono")
+	case 2:
+		parts = append(parts, "stereo")
+	}
+	return strings.Join(parts, " · ")
+}
+
+// InfoChange is one field where the server and the library disagree
+type InfoChange struct {
+	Field  string // "name", "genre", "description", "homepage" or "stream"
+	Old    string
+	New    string
+	Accept bool // Fields the library lacks are accepted up front, differing ones are not
+}
+
+// streamVariantFor returns the variant streamURL plays and what the server says
+// about it. Stations without variants get one for their main URL.
+func streamVariantFor(station RadioStation, streamURL string, info StreamInfo) (int, StreamVariant, StreamVariant, bool) {
+	index := -1
+	old := StreamVariant{URL: streamURL}
+	for i, v := range station.Streams {
+		if v.URL == streamURL {
+			index, old = i, v
+		}
+	}
+	if index < 0 && (len(station.Streams) > 0 || streamURL != station.URL) {
+		return 0, old, old, false
+	}
+
+	// Content types do not tell AAC from AAC+, so a codec is only filled in
+	updated := old
+	if updated.Codec == "" {
+		updated.Codec = info.Codec
+	}
+	if info.Bitrate > 0 {
+		updated.Bitrate = info.Bitrate
+	}
+	return index, old, updated, updated != old
+}
+
+// DiffStreamInfo lists what the server's headers for streamURL would change in
+// the station
+func DiffStreamInfo(station RadioStation, streamURL string, info StreamInfo) []InfoChange {
+	var changes []InfoChange
+	add := func(field, old, value string) {
+		if value != "" && !strings.EqualFold(strings.TrimSpace(old), value) {
+			changes = append(changes, InfoChange{Field: field, Old: old, New: value, Accept: old == ""})
+		}
+	}
+	if !genericICYNames[strings.ToLower(info.Name)] {
+		add("name", station.Name, info.Name)
+	}
+	add("genre", station.Genre, info.Genre)
+	add("description", station.Description, info.Description)
+	add("homepage", station.Homepage, info.Homepage)
+
+	if _, old, updated, ok := streamVariantFor(station, streamURL, info); ok {
+		change := InfoChange{Field: "stream", New: strings.TrimSpace(updated.String()), Accept: old.Bitrate == 0}
+		if old.Codec != "" || old.Bitrate != 0 {
+			change.Old = strings.TrimSpace(old.String())
+		}
+		changes = append(changes, change)
+	}
+	return changes
+}
+
+// ApplyStreamInfo returns the station with the accepted changes made
+func ApplyStreamInfo(station RadioStation, streamURL string, info StreamInfo, changes []InfoChange) RadioStation {
+	for _, change := range changes {
+		if !change.Accept {
+			continue
+		}
+		switch change.Field {
+		case "name":
+			station.Name = change.New
+		case "genre":
+			station.Genre = change.New
+		case "description":
+			station.Description = change.New
+		case "homepage":
+			station.Homepage = change.New
+		case "stream":
+			index, _, updated, ok := streamVariantFor(station, streamURL, info)
+			if !ok {
+				continue
+			}
+			if index < 0 {
+				station.Streams = []StreamVariant{updated}
+			} else {
+				station.Streams = append([]StreamVariant(nil), station.Streams...)
+				station.Streams[index] = updated
+			}
+		}
+	}
+	return station
+}
+
+// streamInfoEntry is what a station's server said when we last connected
+type streamInfoEntry struct {
+	loading bool
+	url     string // The stream the headers came from
+	info    *StreamInfo
+	err     string
+}
+
+// streamInfoMsg delivers the headers captured from a station's stream
+type streamInfoMsg struct {
+	key  string
+	url  string
+	info *StreamInfo
+	err  string
+}
+
+// captureStreamInfoCmd connects to one of a station's streams in the background and reads its headers
+func captureStreamInfoCmd(station RadioStation, streamURL string) tea.Cmd {
+	return func() tea.Msg {
+		station.URL = streamURL
+		result := CheckStation(station, defaultCheckTimeout)
+		msg := streamInfoMsg{key: station.Key(), url: streamURL, info: result.Info}
+		if !result.Connected {
+			msg.err = result.Problem()
+		}
+		return msg
+	}
+}
+
+// recordPlayingInfo keeps what the playing stream's server said, as mpv
+// reported it when connecting
+func (m *Model) recordPlayingInfo(stats StreamStats) {
+	station := m.player.GetCurrentStation()
+	if station == nil || stats.Info == nil || stats.StreamURL == "" {
+		return
+	}
+	entry := &streamInfoEntry{url: stats.StreamURL, info: stats.Info}
+	m.streamInfo[station.Key()] = entry
+	if m.enrich != nil && m.enrich.key == station.Key() && m.enrich.loading {
+		m.showStreamInfo(entry)
+	}
+}
+
+// recordStreamInfo keeps the headers health checks came across
+func (m *Model) recordStreamInfo(results []HealthResult) {
+	for _, r := range results {
+		if entry, ok := m.streamInfo[r.Key]; r.Info == nil || (ok && entry.loading) {
+			continue
+		}
+		m.streamInfo[r.Key] = &streamInfoEntry{url: r.URL, info: r.Info}
+	}
+}
+
+// enrichView compares a library station with what its server says
+type enrichView struct {
+	key     string
+	name    string
+	loading bool
+	url     string
+	info    *StreamInfo
+	changes []InfoChange
+	cursor  int
+	err     string
+}
+
+// RenderEnrichView renders the differences between the server's headers and the library entry
+func RenderEnrichView(e *enrichView) string {
+	muted := lipgloss.NewStyle().Foreground(mutedColor)
+	content := []string{titleStyle.Render("Stream Info: " + truncate(e.name, 30))}
+	switch {
+	case e.loading:
+		return strings.Join(append(content, "Connecting to the stream..."), "\n")
+	case e.err != "":
+		return strings.Join(append(content,
+			lipgloss.NewStyle().Foreground(accentColor).Render(e.err), "", muted.Render("Esc  Close")), "\n")
+	}
+
+	content = append(content, muted.Render(truncate(e.url, 48)))
+	if summary := e.info.Summary(); summary != "" {
+		content = append(content, muted.Render(summary))
+	}
+	content = append(content, "")
+	if len(e.changes) == 0 {
+		return strings.Join(append(content,
+			"The library already matches what the server says", "", muted.Render("Esc  Close")), "\n")
+	}
+
+	removed := lipgloss.NewStyle().Foreground(accentColor)
+	added := lipgloss.NewStyle().Foreground(secondaryColor)
+	for i, change := range e.changes {
+		box := "[ ] "
+		if change.Accept {
+			box = "[x] "
+		}
+		if i == e.cursor {
+			content = append(content, selectedItemStyle.Render("▸ "+box+titleCase(change.Field)))
+		} else {
+			content = append(content, normalItemStyle.Render("  "+box+titleCase(change.Field)))
+		}
+		if change.Old == "" {
+			content = append(content, muted.Render("      - (none)"))
+		} else {
+			content = append(content, removed.Render("      - "+truncate(change.Old, 44)))
+		}
+		content = append(content, added.Render("      + "+truncate(change.New, 44)))
+	}
+
+	content = append(content, "", muted.Render(strings.Join([]string{
+		"Space  Accept/skip the change",
+		"Enter  Save the accepted changes",
+		"Esc    Close",
+	}, "\n")))
+	return strings.Join(content, "\n")
+}
+
+// startEnrich opens the stream info view for the station in the info panel.
+// The playing station's headers come from mpv as it connects; any other
+// station is connected to unless its headers are already known.
+func (m Model) startEnrich() (tea.Model, tea.Cmd) {
+	station := m.infoStation()
+	if station == nil {
+		return m, nil
+	}
+	m.enrich = &enrichView{key: station.Key(), name: station.Name, loading: true}
+	entry, ok := m.streamInfo[station.Key()]
+	current := m.player.GetCurrentStation()
+	switch {
+	case ok && entry.loading:
+		return m, nil
+	case ok && entry.err == "":
+		m.showStreamInfo(entry)
+		return m, nil
+	case current != nil && current.Key() == station.Key():
+		// Shown by recordPlayingInfo once mpv reports the stream
+		return m, nil
+	}
+
+	m.streamInfo[station.Key()] = &streamInfoEntry{loading: true, url: station.URL}
+	return m, captureStreamInfoCmd(*station, station.URL)
+}
+
+// handleStreamInfo stores captured headers and fills in the view waiting for them
+func (m Model) handleStreamInfo(msg streamInfoMsg) (tea.Model, tea.Cmd) {
+	entry := &streamInfoEntry{url: msg.url, info: msg.info, err: msg.err}
+	if msg.err != "" {
+		log.Printf("stream info: %s: %s", msg.key, msg.err)
+	}
+	m.streamInfo[msg.key] = entry
+	if m.enrich != nil && m.enrich.key == msg.key && m.enrich.loading {
+		m.showStreamInfo(entry)
+	}
+	return m, nil
+}
+
+// showStreamInfo diffs captured headers against the library entry of the view's station
+func (m *Model) showStreamInfo(entry *streamInfoEntry) {
+	e := m.enrich
+	e.loading = false
+	e.url = entry.url
+	e.info = entry.info
+	switch {
+	case entry.err != "":
+		e.err = entry.err
+		return
+	case entry.info == nil:
+		e.err = "The server sent no ICY headers"
+		return
+	}
+	for _, s := range m.stations {
+		if s.Key() == e.key {
+			e.changes = DiffStreamInfo(s, entry.url, *entry.info)
+			return
+		}
+	}
+	e.err = "The station is no longer in the library"
+}
+
+// updateEnrich handles keys while the stream info view is open
+func (m Model) updateEnrich(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	e := m.enrich
+	switch msg.String() {
+	case "esc", "I", "q":
+		m.enrich = nil
+
+	case "up", "k":
+		if e.cursor > 0 {
+			e.cursor--
+		}
+
+	case "down", "j":
+		if e.cursor < len(e.changes)-1 {
+			e.cursor++
+		}
+
+	case " ":
+		if e.cursor < len(e.changes) {
+			e.changes[e.cursor].Accept = !e.changes[e.cursor].Accept
+		}
+
+	case "enter":
+		if !e.loading && e.err == "" && len(e.changes) > 0 {
+			return m.applyEnrich()
+		}
+	}
+	return m, nil
+}
+
+// applyEnrich saves the accepted changes to the station file
+func (m Model) applyEnrich() (tea.Model, tea.Cmd) {
+	e := m.enrich
+	var fields []string
+	for _, change := range e.changes {
+		if change.Accept {
+			fields = append(fields, change.Field)
+		}
+	}
+	if len(fields) == 0 {
+		m.enrich = nil
+		m.statusMsg = "No changes accepted"
+		return m, nil
+	}
+
+	for _, s := range m.stations {
+		if s.Key() != e.key {
+			continue
+		}
+		updated := ApplyStreamInfo(s, e.url, *e.info, e.changes)
+		if err := UpdateStation(e.key, updated); err != nil {
+			e.err = firstLine(err.Error())
+			return m, nil
+		}
+		m.enrich = nil
+		m.reloadStations(e.key)
+		m.statusMsg = fmt.Sprintf("Updated %q from its server: %s", updated.Name, strings.Join(fields, ", "))
+		return m, nil
+	}
+	e.err = "The station is no longer in the library"
+	return m, nil
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+)
+
+// Favorites is the user's hand-ordered list of favorite stations. The order
+// is the preset order, so it is kept exactly as the user arranged it.
+type Favorites struct {
+	Keys []string `json:"stations"` // Station keys, first preset first
+}
+
+// favoritesPath returns the location of the favorites file
+func favoritesPath() (string, error) {
+	dir, err := DataDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "favorites.json"), nil
+}
+
+// LoadFavorites reads the favorites file, returning an empty list if it does not exist
+func LoadFavorites() (*Favorites, error) {
+	favorites := &Favorites{}
+
+	path, err := favoritesPath()
+	if err != nil {
+		return favorites, err
+	}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return favorites, nil
+	}
+	if err != nil {
+		return favorites, err
+	}
+	if err := json.Unmarshal(data, favorites); err != nil {
+		return &Favorites{}, err
+	}
+	return favorites, nil
+}
+
+// Save writes the favorites file
+func (f *Favorites) Save() error {
+	path, err := favoritesPath()
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(path, append(data, '\n'), 0644)
+}
+
+// index returns the position of key in the list, or -1
+func (f *Favorites) index(key string) int {
+	for i, k := range f.Keys {
+		if k == key {
+			return i
+		}
+	}
+	return -1
+}
+
+// Has reports whether a station is a favorite
+func (f *Favorites) Has(key string) bool {
+	return f.index(key) >= 0
+}
+
+// Toggle adds a station to the end of the list or removes it, returning whether it is now a favorite
+func (f *Favorites) Toggle(key string) bool {
+	if i := f.index(key); i >= 0 {
+		f.Keys = append(f.Keys[:i], f.Keys[i+1:]...)
+		return false
+	}
+	f.Keys = append(f.Keys, key)
+	return true
+}
+
+// Move shifts a favorite delta places among the favorites that are listed, so
+// keys of deleted or filtered out stations never take up a step. It returns false when the
+// favorite cannot move.
+func (f *Favorites) Move(key string, delta int, stations []RadioStation) bool {
+	present := make(map[string]bool, len(stations))
+	for _, s := range stations {
+		present[s.Key()] = true
+	}
+	var shown []int
+	from := -1
+	for i, k := range f.Keys {
+		if !present[k] {
+			continue
+		}
+		if k == key {
+			from = len(shown)
+		}
+		shown = append(shown, i)
+	}
+	to := from + delta
+	if from < 0 || to < 0 || to >= len(shown) {
+		return false
+	}
+	i, j := shown[from], shown[to]
+	f.Keys[i], f.Keys[j] = f.Keys[j], f.Keys[i]
+	return true
+}
+
+// Replace puts newKey where oldKey is in the list, returning false when oldKey
+// is not a favorite or newKey already is
+func (f *Favorites) Replace(oldKey, newKey string) bool {
+	i := f.index(oldKey)
+	if i < 0 || f.Has(newKey) {
+		return false
+	}
+	f.Keys[i] = newKey
+	return true
+}
+
+// Filter returns the favorite stations in preset order, skipping keys that
+// are no longer in the library
+func (f *Favorites) Filter(stations []RadioStation) []RadioStation {
+	byKey := make(map[string]RadioStation, len(stations))
+	for _, s := range stations {
+		byKey[s.Key()] = s
+	}
+	var favorites []RadioStation
+	for _, key := range f.Keys {
+		if s, ok := byKey[key]; ok {
+			favorites = append(favorites, s)
+		}
+	}
+	return favorites
+}
@@ -0,0 +1,397 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// formField is a single labelled text input
+type formField struct {
+	label string
+	value []rune
+}
+
+// stationForm is the add/edit station dialog
+type stationForm struct {
+	editKey string       // Key of the station being edited; empty when adding
+	base    RadioStation // Station being edited, so fields without inputs survive
+	fields  []formField
+	focus   int
+
+	probing  bool
+	probed   string // URL the last probe ran against
+	probeErr string // Why the last probe failed
+	err      string
+}
+
+const (
+	fieldName = iota
+	fieldURL
+	fieldGenre
+	fieldTags
+	fieldDescription
+)
+
+// newStationForm creates a form, pre-filled when editing an existing station
+func newStationForm(station *RadioStation) *stationForm {
+	f := &stationForm{
+		fields: []formField{
+			{label: "Name"},
+			{label: "URL"},
+			{label: "Genre"},
+			{label: "Tags"},
+			{label: "Description"},
+		},
+	}
+	if station != nil {
+		f.editKey = station.Key()
+		f.base = *station
+		f.fields[fieldName].value = []rune(station.Name)
+		f.fields[fieldURL].value = []rune(station.URL)
+		f.fields[fieldGenre].value = []rune(station.Genre)
+		f.fields[fieldTags].value = []rune(strings.Join(station.Tags, ", "))
+		f.fields[fieldDescription].value = []rune(station.Description)
+	}
+	return f
+}
+
+// value returns the trimmed text of a field
+func (f *stationForm) value(field int) string {
+	return strings.TrimSpace(string(f.fields[field].value))
+}
+
+// station builds a RadioStation from the form contents
+func (f *stationForm) station() RadioStation {
+	station := f.base
+	station.Name = f.value(fieldName)
+	station.URL = f.value(fieldURL)
+	if station.URL != f.base.URL {
+		// The variants and mirrors belong to the old stream and would keep playing it
+		station.Streams = nil
+		station.Mirrors = nil
+	}
+	station.Genre = f.value(fieldGenre)
+	station.Tags = splitTags(f.value(fieldTags))
+	station.Description = f.value(fieldDescription)
+	return station
+}
+
+// validate checks the fields that do not need the network
+func (f *stationForm) validate() string {
+	if f.value(fieldName) == "" {
+		return "Name is required"
+	}
+	if f.value(fieldURL) == "" {
+		return "URL is required"
+	}
+	if msg := checkStreamURL(f.value(fieldURL)); msg != "" {
+		return "URL " + msg
+	}
+	return ""
+}
+
+// handleKey edits the focused field; it returns true when the key was consumed
+func (f *stationForm) handleKey(msg tea.KeyMsg) bool {
+	field := &f.fields[f.focus]
+	switch msg.Type {
+	case tea.KeyTab, tea.KeyDown:
+		f.focus = (f.focus + 1) % len(f.fields)
+	case tea.KeyShiftTab, tea.KeyUp:
+		f.focus = (f.focus + len(f.fields) - 1) % len(f.fields)
+	case tea.KeyBackspace:
+		if len(field.value) > 0 {
+			field.value = field.value[:len(field.value)-1]
+		}
+	case tea.KeyCtrlU:
+		field.value = nil
+	case tea.KeySpace:
+		field.value = append(field.value, ' ')
+	case tea.KeyRunes:
+		field.value = append(field.value, msg.Runes...)
+	default:
+		return false
+	}
+	return true
+}
+
+// probeResultMsg delivers the outcome of probing a URL entered in the form
+type probeResultMsg ProbeResult
+
+// probeURL checks a stream URL in the background
+func probeURL(url string) tea.Cmd {
+	return func() tea.Msg {
+		return probeResultMsg(ProbeStream(url))
+	}
+}
+
+// RenderStationForm renders the add/edit station dialog
+func RenderStationForm(f *stationForm) string {
+	title := "Add Station"
+	if f.editKey != "" {
+		title = "Edit Station"
+	}
+	content := []string{titleStyle.Render(title)}
+
+	for i, field := range f.fields {
+		label := fmt.Sprintf("%-12s", field.label+":")
+		value := string(field.value)
+		if i == f.focus {
+			content = append(content, selectedItemStyle.Render(label+value+"█"))
+		} else {
+			content = append(content, normalItemStyle.Render(label+value))
+		}
+	}
+
+	content = append(content, "")
+	switch {
+	case f.probing:
+		content = append(content, "Probing stream...")
+	case f.err != "":
+		content = append(content, lipgloss.NewStyle().Foreground(accentColor).Render(f.err))
+	case f.probeErr != "":
+		content = append(content, lipgloss.NewStyle().Foreground(accentColor).Render(
+			"Probe failed: "+f.probeErr+"\nPress Enter again to save anyway"))
+	}
+
+	content = append(content, "",
+		lipgloss.NewStyle().Foreground(mutedColor).Render(strings.Join([]string{
+			"Tab/↑/↓  Next/previous field",
+			"Enter    Probe URL and save",
+			"Ctrl+U   Clear field",
+			"Esc      Cancel",
+		}, "\n")))
+
+	return strings.Join(content, "\n")
+}
+
+// RenderConfirm renders a yes/no question
+func RenderConfirm(question string) string {
+	return strings.Join([]string{
+		titleStyle.Render("Confirm"),
+		question,
+		"",
+		lipgloss.NewStyle().Foreground(mutedColor).Render("y  Yes    n/Esc  No"),
+	}, "\n")
+}
+
+// updateForm handles keys while the add/edit station form is open
+func (m Model) updateForm(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	f := m.form
+	switch msg.Type {
+	case tea.KeyEsc:
+		m.form = nil
+		return m, nil
+
+	case tea.KeyEnter:
+		if f.probing {
+			return m, nil
+		}
+		if msg := f.validate(); msg != "" {
+			f.err = msg
+			return m, nil
+		}
+		f.err = ""
+
+		url := f.value(fieldURL)
+		if f.probeErr != "" && f.probed == url {
+			// The user has seen the probe failure and wants to save regardless
+			return m.saveForm()
+		}
+		f.probing = true
+		f.probed = url
+		f.probeErr = ""
+		return m, probeURL(url)
+	}
+
+	if f.handleKey(msg) {
+		f.err = ""
+	}
+	return m, nil
+}
+
+// handleProbeResult saves the form when its URL probed fine, or reports the problem
+func (m Model) handleProbeResult(result ProbeResult) (tea.Model, tea.Cmd) {
+	f := m.form
+	if f == nil || !f.probing || result.URL != f.probed {
+		// Stale result from a form that was closed or changed
+		return m, nil
+	}
+	f.probing = false
+
+	if !result.OK() {
+		f.probeErr = result.Problem()
+		return m, nil
+	}
+	return m.saveForm()
+}
+
+// saveForm writes the form's station to the station file and reloads the library
+func (m Model) saveForm() (tea.Model, tea.Cmd) {
+	f := m.form
+	station := f.station()
+
+	var err error
+	key := station.Key()
+	if f.editKey != "" {
+		key = f.editKey
+		err = UpdateStation(f.editKey, station)
+	} else {
+		err = AddStation(station)
+	}
+	if err != nil {
+		f.err = err.Error()
+		return m, nil
+	}
+
+	m.form = nil
+	if f.editKey == "" {
+		m.includeInProfile(key)
+	}
+	m.reloadStations(key)
+	m.statusMsg = fmt.Sprintf("Saved %q", station.Name)
+	return m, nil
+}
+
+// updateConfirmDelete handles the yes/no answer for deleting a station
+func (m Model) updateConfirmDelete(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "y", "Y":
+		station := m.confirmDelete
+		m.confirmDelete = nil
+
+		// Keep the highlight near where the deleted station was
+		neighbor := m.neighborKey(station.Key())
+
+		if err := DeleteStation(station.Key()); err != nil {
+			m.statusMsg = "⚠ " + firstLine(err.Error())
+			return m, nil
+		}
+		m.reloadStations(neighbor)
+		m.statusMsg = fmt.Sprintf("Deleted %q", station.Name)
+
+	case "n", "N", "esc", "q":
+		m.confirmDelete = nil
+	}
+	return m, nil
+}
+
+// promptKind identifies what a text prompt's answer is used for
+type promptKind int
+
+const (
+	promptImport promptKind = iota
+	promptExport
+	promptTagFilter
+	promptNewProfile
+)
+
+// textPrompt is a single-line question, such as a file path
+type textPrompt struct {
+	kind  promptKind
+	title string
+	hint  string
+	input formField
+	err   string
+}
+
+// RenderPrompt renders a single-line text prompt
+func RenderPrompt(p *textPrompt) string {
+	content := []string{
+		titleStyle.Render(p.title),
+		selectedItemStyle.Render(fmt.Sprintf("%s: %s█", p.input.label, string(p.input.value))),
+		"",
+	}
+	if p.err != "" {
+		content = append(content, lipgloss.NewStyle().Foreground(accentColor).Render(p.err), "")
+	}
+	content = append(content, lipgloss.NewStyle().Foreground(mutedColor).Render(p.hint+"\nEnter  OK    Esc  Cancel"))
+	return strings.Join(content, "\n")
+}
+
+// updatePrompt handles keys while a text prompt is open
+func (m Model) updatePrompt(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	p := m.prompt
+	switch msg.Type {
+	case tea.KeyEsc:
+		m.prompt = nil
+		return m, nil
+
+	case tea.KeyEnter:
+		answer := strings.TrimSpace(string(p.input.value))
+		if answer == "" && p.kind != promptTagFilter {
+			return m, nil
+		}
+		if p.kind == promptImport || p.kind == promptExport {
+			// Only file names get ~ expanded; tag filters and profile names are taken as typed
+			answer = expandHome(answer)
+		}
+		return m.submitPrompt(p, answer)
+
+	case tea.KeyBackspace:
+		if len(p.input.value) > 0 {
+			p.input.value = p.input.value[:len(p.input.value)-1]
+		}
+	case tea.KeyCtrlU:
+		p.input.value = nil
+	case tea.KeySpace:
+		p.input.value = append(p.input.value, ' ')
+	case tea.KeyRunes:
+		p.input.value = append(p.input.value, msg.Runes...)
+	}
+	p.err = ""
+	return m, nil
+}
+
+// submitPrompt carries out the action a prompt was opened for
+func (m Model) submitPrompt(p *textPrompt, answer string) (tea.Model, tea.Cmd) {
+	switch p.kind {
+	case promptImport:
+		stations, err := ParseStationList(answer)
+		if err != nil {
+			p.err = err.Error()
+			return m, nil
+		}
+		result, err := ImportStations(stations)
+		if err != nil {
+			p.err = firstLine(err.Error())
+			return m, nil
+		}
+		key := ""
+		if station := m.selectedStation(); station != nil {
+			key = station.Key()
+		}
+		m.includeInProfile(result.Keys...)
+		m.reloadStations(key)
+		m.statusMsg = fmt.Sprintf("Imported %s: %s", answer, result)
+
+	case promptExport:
+		stations := m.listStations()
+		if err := ExportStationList(answer, stations); err != nil {
+			p.err = err.Error()
+			return m, nil
+		}
+		m.statusMsg = fmt.Sprintf("Exported %d stations to %s", len(stations), answer)
+
+	case promptTagFilter:
+		m.tagFilter = ParseTagFilter(answer)
+		key := ""
+		if station := m.selectedStation(); station != nil {
+			key = station.Key()
+		}
+		m.refreshList(key)
+
+	case promptNewProfile:
+		m.prompt = nil
+		if err := m.createProfile(answer); err != nil {
+			m.prompt = p
+			p.err = err.Error()
+		}
+		return m, nil
+	}
+
+	m.prompt = nil
+	return m, nil
+}
@@ -0,0 +1,383 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+const (
+	// defaultCheckParallel bounds how many stations are probed at once
+	defaultCheckParallel = 8
+	// defaultCheckTimeout bounds each station's whole check, playlist included
+	defaultCheckTimeout = 10 * time.Second
+	// slowFirstByte is when a stream that does answer counts as degraded
+	slowFirstByte = 3 * time.Second
+	// maxPlaylistHops stops playlists that point at playlists from looping forever
+	maxPlaylistHops = 3
+)
+
+// HealthStatus summarizes a station check
+type HealthStatus string
+
+const (
+	HealthOK       HealthStatus = "ok"
+	HealthDegraded HealthStatus = "degraded" // Plays, but slowly or without ICY metadata
+	HealthDead     HealthStatus = "dead"
+)
+
+// HealthResult records what each step of a station check found
+type HealthResult struct {
+	Key              string       `json:"key"`
+	Name             string       `json:"name"`
+	URL              string       `json:"url"`
+	Playlist         bool         `json:"playlist"`                    // The URL pointed at a playlist
+	PlaylistResolved bool         `json:"playlist_resolved,omitempty"` // ...which named a stream
+	StreamURL        string       `json:"stream_url,omitempty"`        // Where the audio came from
+	Connected        bool         `json:"connected"`
+	HTTPStatus       int          `json:"http_status,omitempty"`
+	ContentType      string       `json:"content_type,omitempty"`
+	AudioContent     bool         `json:"audio_content"`
+	FirstBytes       bool         `json:"first_bytes"`
+	FirstByteMillis  int64        `json:"first_byte_ms"`
+	ICY              bool         `json:"icy"`
+	ICYName          string       `json:"icy_name,omitempty"`
+	Info             *StreamInfo  `json:"info,omitempty"` // What the server's headers say about the station
+	Error            string       `json:"error,omitempty"`
+	Status           HealthStatus `json:"status"`
+	CheckedAt        time.Time    `json:"checked_at"`
+}
+
+// Problem describes the first thing that went wrong, or "" for a healthy station
+func (r HealthResult) Problem() string {
+	switch {
+	case r.Error != "":
+		return r.Error
+	case r.Playlist && !r.PlaylistResolved:
+		return "playlist names no stream"
+	case !r.Connected:
+		return "could not connect"
+	case r.HTTPStatus != http.StatusOK:
+		return fmt.Sprintf("server answered %d %s", r.HTTPStatus, http.StatusText(r.HTTPStatus))
+	case !r.AudioContent:
+		return fmt.Sprintf("content type %q is not audio", r.ContentType)
+	case !r.FirstBytes:
+		return "no audio data"
+	case time.Duration(r.FirstByteMillis)*time.Millisecond > slowFirstByte:
+		return fmt.Sprintf("slow start (%d ms)", r.FirstByteMillis)
+	case !r.ICY:
+		return "no ICY headers"
+	}
+	return ""
+}
+
+// status grades the result once every step has run
+func (r HealthResult) status() HealthStatus {
+	if !r.Connected || !r.AudioContent || !r.FirstBytes || (r.Playlist && !r.PlaylistResolved) {
+		return HealthDead
+	}
+	if !r.ICY || time.Duration(r.FirstByteMillis)*time.Millisecond > slowFirstByte {
+		return HealthDegraded
+	}
+	return HealthOK
+}
+
+// isPlaylistURL reports whether a URL names a playlist by its extension
+func isPlaylistURL(u string) bool {
+	parsed, err := url.Parse(u)
+	if err != nil {
+		return false
+	}
+	switch strings.ToLower(path.Ext(parsed.Path)) {
+	case ".pls", ".m3u", ".m3u8":
+		return true
+	}
+	return false
+}
+
+// isPlaylistContentType reports whether a server answered with a playlist rather than audio
+func isPlaylistContentType(contentType string) bool {
+	ct := strings.ToLower(strings.TrimSpace(strings.Split(contentType, ";")[0]))
+	switch ct {
+	case "audio/x-scpls", "audio/scpls", "application/pls+xml", "audio/x-mpegurl", "audio/mpegurl",
+		"application/x-mpegurl", "application/vnd.apple.mpegurl":
+		return true
+	}
+	return false
+}
+
+// CheckStation probes a station's main URL, following playlists to the stream
+func CheckStation(station RadioStation, timeout time.Duration) (result HealthResult) {
+	result = HealthResult{Key: station.Key(), Name: station.Name, URL: station.URL, CheckedAt: time.Now()}
+	defer func() { result.Status = result.status() }()
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	target := station.URL
+	for hop := 0; ; hop++ {
+		req, err := http.NewRequestWithContext(ctx, "GET", target, nil)
+		if err != nil {
+			result.Error = err.Error()
+			return result
+		}
+		req.Header.Set("Icy-MetaData", "1")
+		req.Header.Set("User-Agent", "goradio-hub/"+Version)
+		station.Settings.setHeaders(req)
+
+		started := time.Now()
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			result.Error = err.Error()
+			return result
+		}
+
+		playlist := isPlaylistURL(target) || isPlaylistContentType(resp.Header.Get("Content-Type"))
+		if playlist && hop < maxPlaylistHops {
+			result.Playlist = true
+			next, err := readPlaylistTarget(resp, target)
+			resp.Body.Close()
+			if err != nil {
+				result.Error = "playlist: " + err.Error()
+				return result
+			}
+			result.PlaylistResolved = true
+			target = next
+			continue
+		}
+
+		defer resp.Body.Close()
+		result.Connected = true
+		result.StreamURL = target
+		result.HTTPStatus = resp.StatusCode
+		result.ContentType = resp.Header.Get("Content-Type")
+		result.AudioContent = resp.StatusCode == http.StatusOK &&
+			isStreamContentType(result.ContentType) && !isPlaylistContentType(result.ContentType)
+		result.ICYName = resp.Header.Get("Icy-Name")
+		result.Info = ParseStreamInfo(resp.Header)
+		for name := range resp.Header {
+			if strings.HasPrefix(strings.ToLower(name), "icy-") {
+				result.ICY = true
+				break
+			}
+		}
+
+		if resp.StatusCode == http.StatusOK {
+			buf := make([]byte, 512)
+			if _, err := io.ReadAtLeast(resp.Body, buf, 1); err != nil {
+				result.Error = fmt.Sprintf("no data received: %v", err)
+				return result
+			}
+			result.FirstBytes = true
+			result.FirstByteMillis = time.Since(started).Milliseconds()
+		}
+		return result
+	}
+}
+
+// readPlaylistTarget returns the first entry of a PLS or M3U playlist,
+// resolved against the playlist's own URL
+func readPlaylistTarget(resp *http.Response, playlistURL string) (string, error) {
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("server answered %s", resp.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	if err != nil {
+		return "", err
+	}
+
+	var entries []RadioStation
+	if format, _ := playlistFormatFor(path.Base(playlistURL), data); format == FormatPLS {
+		entries = ParsePLS(data)
+	} else {
+		entries = ParseM3U(data)
+	}
+	if len(entries) == 0 {
+		return "", fmt.Errorf("no entries")
+	}
+
+	base, err := url.Parse(playlistURL)
+	if err != nil {
+		return entries[0].URL, nil
+	}
+	ref, err := url.Parse(entries[0].URL)
+	if err != nil {
+		return "", err
+	}
+	return base.ResolveReference(ref).String(), nil
+}
+
+// CheckStations probes stations concurrently, at most parallel at a time.
+// Results come back in the order of the input.
+func CheckStations(stations []RadioStation, parallel int, timeout time.Duration) []HealthResult {
+	if parallel <= 0 {
+		parallel = defaultCheckParallel
+	}
+	results := make([]HealthResult, len(stations))
+	slots := make(chan struct{}, parallel)
+
+	var wg sync.WaitGroup
+	for i := range stations {
+		wg.Add(1)
+		slots <- struct{}{}
+		go func(i int) {
+			defer func() {
+				<-slots
+				wg.Done()
+			}()
+			results[i] = CheckStation(stations[i], timeout)
+		}(i)
+	}
+	wg.Wait()
+	return results
+}
+
+// HealthSummary counts results by status
+type HealthSummary struct {
+	OK       int `json:"ok"`
+	Degraded int `json:"degraded"`
+	Dead     int `json:"dead"`
+}
+
+// SummarizeHealth counts how many stations ended in each status
+func SummarizeHealth(results []HealthResult) HealthSummary {
+	var s HealthSummary
+	for _, r := range results {
+		switch r.Status {
+		case HealthOK:
+			s.OK++
+		case HealthDegraded:
+			s.Degraded++
+		default:
+			s.Dead++
+		}
+	}
+	return s
+}
+
+func (s HealthSummary) String() string {
+	return fmt.Sprintf("%d ok · %d degraded · %d dead", s.OK, s.Degraded, s.Dead)
+}
+
+// healthReport is the TUI view of a health check run
+type healthReport struct {
+	loading bool
+	total   int
+	results []HealthResult
+	start   int
+}
+
+// healthResultsMsg delivers a finished health check run
+type healthResultsMsg []HealthResult
+
+// checkStationsCmd runs a health check in the background
+func checkStationsCmd(stations []RadioStation) tea.Cmd {
+	return func() tea.Msg {
+		return healthResultsMsg(CheckStations(stations, defaultCheckParallel, defaultCheckTimeout))
+	}
+}
+
+// RenderHealthBadge shows a status as a colored dot; unknown status renders as blank
+func RenderHealthBadge(status HealthStatus) string {
+	var color lipgloss.Color
+	switch status {
+	case HealthOK:
+		color = lipgloss.Color("#04B575")
+	case HealthDegraded:
+		color = lipgloss.Color("#FFCC00")
+	case HealthDead:
+		color = lipgloss.Color("#FF4F4F")
+	default:
+		return " "
+	}
+	return lipgloss.NewStyle().Foreground(color).Render("●")
+}
+
+// RenderHealthReport renders the results of a health check, problems first
+func RenderHealthReport(r *healthReport, rows int) string {
+	content := []string{titleStyle.Render("Station Health")}
+	if r.loading {
+		content = append(content, fmt.Sprintf("Checking %d stations...", r.total))
+		return strings.Join(content, "\n")
+	}
+
+	content = append(content, SummarizeHealth(r.results).String(), "")
+	end := min(len(r.results), r.start+rows)
+	for _, result := range r.results[r.start:end] {
+		line := RenderHealthBadge(result.Status) + " " + truncate(result.Name, 24)
+		if problem := result.Problem(); problem != "" {
+			line += lipgloss.NewStyle().Foreground(mutedColor).Render(" - " + truncate(firstLine(problem), 40))
+		} else if result.FirstBytes {
+			line += lipgloss.NewStyle().Foreground(mutedColor).Render(fmt.Sprintf(" - %d ms", result.FirstByteMillis))
+		}
+		content = append(content, line)
+	}
+	if len(r.results) > rows {
+		content = append(content, lipgloss.NewStyle().Foreground(mutedColor).Render(
+			fmt.Sprintf("%d-%d of %d", r.start+1, end, len(r.results))))
+	}
+
+	content = append(content, "", lipgloss.NewStyle().Foreground(mutedColor).Render(strings.Join([]string{
+		"↑/↓  Scroll",
+		"r    Check again",
+		"Esc  Close",
+	}, "\n")))
+	return strings.Join(content, "\n")
+}
+
+// sortHealthResults puts dead stations first, then degraded, keeping list order within each
+func sortHealthResults(results []HealthResult) {
+	rank := map[HealthStatus]int{HealthDead: 0, HealthDegraded: 1, HealthOK: 2}
+	sort.SliceStable(results, func(i, j int) bool {
+		return rank[results[i].Status] < rank[results[j].Status]
+	})
+}
+
+// startHealthCheck opens the report and checks the stations in the current list
+func (m Model) startHealthCheck() (tea.Model, tea.Cmd) {
+	stations := m.listStations()
+	m.health = &healthReport{loading: true, total: len(stations)}
+	return m, checkStationsCmd(stations)
+}
+
+// updateHealthReport handles keys while the health report is open
+func (m Model) updateHealthReport(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	r := m.health
+	switch msg.String() {
+	case "esc", "H", "q":
+		m.health = nil
+	case "up", "k":
+		r.start = max(0, r.start-1)
+	case "down", "j":
+		r.start = max(0, min(len(r.results)-m.visibleCount, r.start+1))
+	case "r":
+		if !r.loading {
+			return m.startHealthCheck()
+		}
+	}
+	return m, nil
+}
+
+// handleHealthResults records the results and shows them if the report is still open
+func (m Model) handleHealthResults(results []HealthResult) (tea.Model, tea.Cmd) {
+	// Manual checks count towards uptime just like the background ones
+	m.recordAvailability(results)
+	if m.health == nil {
+		return m, nil
+	}
+	sortHealthResults(results)
+	m.health.loading = false
+	m.health.results = results
+	m.health.start = 0
+	return m, nil
+}
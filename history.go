@@ -0,0 +1,208 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// maxHistory caps how many plays are kept; the oldest are dropped first
+const maxHistory = 1000
+
+// historyMaxGap is the longest pause between Track calls that still counts as
+// listening; longer gaps (a suspended laptop, a stalled UI) are not counted
+const historyMaxGap = 10 * time.Second
+
+// historySaveInterval is how often a running play's duration is written out
+const historySaveInterval = time.Minute
+
+// PlayRecord is one listening session
+type PlayRecord struct {
+	Key     string    `json:"key"`
+	Name    string    `json:"name"`
+	URL     string    `json:"url"` // So stations no longer in the library can still be replayed
+	Started time.Time `json:"started"`
+	Seconds int       `json:"seconds"` // Time spent actually playing
+}
+
+// History is the persistent log of what was played, oldest first
+type History struct {
+	Plays []PlayRecord `json:"plays"`
+
+	open    bool      // The last record is still being listened to
+	playing time.Time // When the open record last counted time
+	saved   time.Time
+}
+
+// HistoryEntry summarizes all plays of one station
+type HistoryEntry struct {
+	Station    RadioStation
+	LastPlayed time.Time
+	Listened   time.Duration
+	Plays      int
+}
+
+// historyPath returns the location of the play history
+func historyPath() (string, error) {
+	dir, err := StateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "history.json"), nil
+}
+
+// LoadHistory reads the play history, returning an empty one if it does not exist
+func LoadHistory() (*History, error) {
+	history := &History{}
+
+	path, err := historyPath()
+	if err != nil {
+		return history, err
+	}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return history, nil
+	}
+	if err != nil {
+		return history, err
+	}
+	if err := json.Unmarshal(data, history); err != nil {
+		return &History{}, err
+	}
+	return history, nil
+}
+
+// Save writes the play history
+func (h *History) Save() error {
+	path, err := historyPath()
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(h, "", "  ")
+	if err != nil {
+		return err
+	}
+	h.saved = time.Now()
+	return writeFileAtomic(path, append(data, '\n'), 0644)
+}
+
+// Track is called regularly with what the player is doing. It opens a record
+// when a station starts playing, adds up the time it plays, and closes the
+// record when playback stops or moves to another station.
+func (h *History) Track(station *RadioStation, playing bool, now time.Time) {
+	if h.open {
+		last := &h.Plays[len(h.Plays)-1]
+		if elapsed := now.Sub(h.playing); !h.playing.IsZero() && elapsed <= historyMaxGap {
+			last.Seconds += int(elapsed.Round(time.Second) / time.Second)
+		}
+		h.playing = time.Time{}
+		if playing && station != nil && station.Key() == last.Key {
+			h.playing = now
+			if now.Sub(h.saved) >= historySaveInterval {
+				h.save()
+			}
+			return
+		}
+		h.open = false
+		h.save()
+	}
+
+	if !playing || station == nil {
+		return
+	}
+	h.Plays = append(h.Plays, PlayRecord{Key: station.Key(), Name: station.Name, URL: station.URL, Started: now})
+	if len(h.Plays) > maxHistory {
+		h.Plays = h.Plays[len(h.Plays)-maxHistory:]
+	}
+	h.open = true
+	h.playing = now
+	h.save()
+}
+
+// save writes the history, logging rather than interrupting playback on failure
+func (h *History) save() {
+	if err := h.Save(); err != nil {
+		log.Printf("history: saving failed: %v", err)
+	}
+}
+
+// Entries summarizes the history per station, most recently played first.
+// Stations are taken from the library when present so overrides apply.
+func (h *History) Entries(library []RadioStation) []HistoryEntry {
+	byKey := make(map[string]RadioStation, len(library))
+	for _, s := range library {
+		byKey[s.Key()] = s
+	}
+
+	index := make(map[string]int)
+	var entries []HistoryEntry
+	for _, play := range h.Plays {
+		i, ok := index[play.Key]
+		if !ok {
+			station, inLibrary := byKey[play.Key]
+			if !inLibrary {
+				station = RadioStation{ID: play.Key, Name: play.Name, URL: play.URL}
+			}
+			i = len(entries)
+			index[play.Key] = i
+			entries = append(entries, HistoryEntry{Station: station})
+		}
+		e := &entries[i]
+		e.Plays++
+		e.Listened += time.Duration(play.Seconds) * time.Second
+		if play.Started.After(e.LastPlayed) {
+			e.LastPlayed = play.Started
+		}
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].LastPlayed.After(entries[j].LastPlayed)
+	})
+	return entries
+}
+
+// Previous returns the key of the station played before current, or "" if none
+func (h *History) Previous(current string) string {
+	for i := len(h.Plays) - 1; i >= 0; i-- {
+		if h.Plays[i].Key != current {
+			return h.Plays[i].Key
+		}
+	}
+	return ""
+}
+
+// timeAgo renders how long ago t was, e.g. "5 minutes ago"
+func timeAgo(t time.Time, now time.Time) string {
+	d := now.Sub(t)
+	plural := func(n int, unit string) string {
+		if n == 1 {
+			return fmt.Sprintf("1 %s ago", unit)
+		}
+		return fmt.Sprintf("%d %ss ago", n, unit)
+	}
+	switch {
+	case d < time.Minute:
+		return "just now"
+	case d < time.Hour:
+		return plural(int(d/time.Minute), "minute")
+	case d < 24*time.Hour:
+		return plural(int(d/time.Hour), "hour")
+	default:
+		return plural(int(d/(24*time.Hour)), "day")
+	}
+}
+
+// formatListened renders a total listening time, e.g. "1h 20m"
+func formatListened(d time.Duration) string {
+	switch {
+	case d < time.Minute:
+		return "<1m"
+	case d < time.Hour:
+		return fmt.Sprintf("%dm", int(d/time.Minute))
+	}
+	return fmt.Sprintf("%dh %dm", int(d/time.Hour), int(d%time.Hour/time.Minute))
+}
@@ -0,0 +1,592 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// The user's station file lives next to config.json and looks like:
+//
+//	{
+//	  "version": 2,
+//	  "include_defaults": true,
+//	  "remove": ["drone-zone"],
+//	  "stations": [
+//	    {"name": "My Stream", "url": "https://example.com/live.mp3", "genre": "Jazz"},
+//	    {"id": "groove-salad", "description": "Overrides just this field"}
+//	  ]
+//	}
+//
+// Merge policy:
+//   - Built-in stations come first, in their built-in order, unless include_defaults is false.
+//   - Built-in stations whose key is listed in "remove" are dropped.
+//   - A user entry whose key (id, or the name slug) matches a built-in station overrides
+//     only the fields it sets; the station keeps its position. An entry that changes the
+//     url also replaces the stream variants and mirrors, which belong to the old stream.
+//   - All other user entries are appended in file order.
+//
+// Older files are upgraded on load; see MigrateStationFile.
+
+// stationFile is the on-disk layout of the user's station file
+type stationFile struct {
+	Version         int               `json:"version,omitempty"` // stationFileVersion; missing in version 1 files
+	IncludeDefaults *bool             `json:"include_defaults,omitempty"`
+	Remove          []string          `json:"remove,omitempty"`
+	Stations        []json.RawMessage `json:"stations"`
+}
+
+// StationFileProblem is a single validation failure at a position in the station file
+type StationFileProblem struct {
+	Line int
+	Col  int
+	Msg  string
+}
+
+// StationFileError lists every problem found while loading the station file
+type StationFileError struct {
+	Path     string
+	Problems []StationFileProblem
+}
+
+func (e *StationFileError) Error() string {
+	lines := make([]string, len(e.Problems))
+	for i, p := range e.Problems {
+		lines[i] = fmt.Sprintf("%s:%d:%d: %s", e.Path, p.Line, p.Col, p.Msg)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// stationsPath returns the location of the user's station file
+func stationsPath() (string, error) {
+	dir, err := ConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "stations.json"), nil
+}
+
+// LoadStations merges the built-in stations with the user's station file.
+// When the file is missing the defaults are returned; when it is invalid the
+// defaults are returned together with a *StationFileError.
+func LoadStations() ([]RadioStation, error) {
+	defaults := BuiltinStations()
+
+	path, err := stationsPath()
+	if err != nil {
+		return defaults, err
+	}
+
+	data, err := readStationFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return defaults, nil
+		}
+		return defaults, err
+	}
+
+	file, entries, err := parseStationFile(path, data, defaults)
+	if err != nil {
+		return defaults, err
+	}
+	return mergeStations(defaults, file, entries), nil
+}
+
+// readStationFile reads the user's station file, upgrading it to
+// stationFileVersion first. The file is only rewritten once a backup of the
+// original (stations.json.v1.bak for a version 1 file) is safely on disk and
+// the upgraded file validates; otherwise the original is read as it is.
+func readStationFile(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	migrated, from, err := MigrateStationFile(data, stationFileVersion)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	if bytes.Equal(migrated, data) {
+		return data, nil
+	}
+	if _, _, err := parseStationFile(path, migrated, BuiltinStations()); err != nil {
+		log.Printf("stations: not upgrading %s from version %d until it loads: %v", path, from, err)
+		return data, nil
+	}
+
+	backup := fmt.Sprintf("%s.v%d.bak", path, from)
+	if _, err := os.Stat(backup); os.IsNotExist(err) {
+		if err := writeFileAtomic(backup, data, 0644); err != nil {
+			log.Printf("stations: not upgrading %s, backup failed: %v", path, err)
+			return data, nil
+		}
+	}
+	if err := writeFileAtomic(path, migrated, 0644); err != nil {
+		log.Printf("stations: saving upgraded %s failed: %v", path, err)
+		return migrated, nil
+	}
+	log.Printf("stations: upgraded %s from version %d to %d, the original is in %s", path, from, stationFileVersion, backup)
+	return migrated, nil
+}
+
+// parseStationFile decodes and validates the station file, collecting every problem
+func parseStationFile(path string, data []byte, defaults []RadioStation) (*stationFile, []RadioStation, error) {
+	fileErr := &StationFileError{Path: path}
+	problem := func(offset int, format string, args ...interface{}) {
+		line, col := lineCol(data, offset)
+		fileErr.Problems = append(fileErr.Problems, StationFileProblem{Line: line, Col: col, Msg: fmt.Sprintf(format, args...)})
+	}
+
+	var file stationFile
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&file); err != nil {
+		problem(jsonErrorOffset(err, data), "%s", jsonErrorMessage(err))
+		return nil, nil, fileErr
+	}
+
+	offsets, err := stationOffsets(data)
+	if err != nil {
+		problem(0, "%v", err)
+		return nil, nil, fileErr
+	}
+
+	// Entries that override a built-in station may be partial; new stations may not
+	builtin := make(map[string]bool)
+	if file.IncludeDefaults == nil || *file.IncludeDefaults {
+		for _, d := range defaults {
+			builtin[d.Key()] = true
+		}
+	}
+	for _, key := range file.Remove {
+		delete(builtin, key)
+	}
+
+	seen := make(map[string]int)
+	var stations []RadioStation
+	for i, raw := range file.Stations {
+		start := offsets[i]
+
+		var station RadioStation
+		sdec := json.NewDecoder(bytes.NewReader(raw))
+		sdec.DisallowUnknownFields()
+		if err := sdec.Decode(&station); err != nil {
+			problem(start+jsonErrorOffset(err, raw), "station %d: %s", i+1, jsonErrorMessage(err))
+			continue
+		}
+
+		for _, msg := range validateStation(&station) {
+			problem(start+fieldOffset(raw, msg.field), "station %d: %s", i+1, msg.text)
+		}
+
+		if station.Name == "" && station.ID == "" {
+			continue
+		}
+		key := station.Key()
+		if !builtin[key] {
+			if station.Name == "" {
+				problem(start, "station %d: new station %q needs a \"name\"", i+1, key)
+			}
+			if station.URL == "" {
+				problem(start, "station %d: new station %q needs a \"url\"", i+1, key)
+			}
+		}
+		if prev, ok := seen[key]; ok {
+			problem(start, "station %d: duplicate key %q (first defined as station %d)", i+1, key, prev)
+			continue
+		}
+		seen[key] = i + 1
+		stations = append(stations, station)
+	}
+
+	if len(fileErr.Problems) > 0 {
+		sort.SliceStable(fileErr.Problems, func(i, j int) bool {
+			a, b := fileErr.Problems[i], fileErr.Problems[j]
+			return a.Line < b.Line || (a.Line == b.Line && a.Col < b.Col)
+		})
+		return nil, nil, fileErr
+	}
+	return &file, stations, nil
+}
+
+// fieldProblem is a validation message attached to a JSON field name
+type fieldProblem struct {
+	field string
+	text  string
+}
+
+// validateStation checks the fields of a single user entry. Entries that only
+// override a built-in station by id may leave name and url empty.
+func validateStation(s *RadioStation) []fieldProblem {
+	var problems []fieldProblem
+
+	if s.Name == "" && s.ID == "" {
+		problems = append(problems, fieldProblem{"", "needs a \"name\" or an \"id\""})
+	}
+	// Any key Key could have made is a valid id, so stations saved by key keep loading
+	if s.ID != "" && s.ID != nameKey(s.ID) {
+		problems = append(problems, fieldProblem{"id", fmt.Sprintf("id %q must be lowercase without spaces (try %q)", s.ID, nameKey(s.ID))})
+	}
+	if s.URL != "" {
+		if msg := checkStreamURL(s.URL); msg != "" {
+			problems = append(problems, fieldProblem{"url", "url " + msg})
+		}
+	}
+	for i, v := range s.Streams {
+		if v.URL == "" {
+			problems = append(problems, fieldProblem{"streams", fmt.Sprintf("stream %d has no url", i+1)})
+		} else if msg := checkStreamURL(v.URL); msg != "" {
+			problems = append(problems, fieldProblem{"streams", fmt.Sprintf("stream %d url %s", i+1, msg)})
+		}
+		if v.Bitrate < 0 {
+			problems = append(problems, fieldProblem{"streams", fmt.Sprintf("stream %d has a negative bitrate", i+1)})
+		}
+	}
+	for _, tag := range s.Tags {
+		if strings.TrimSpace(tag) == "" {
+			problems = append(problems, fieldProblem{"tags", "tags must not be empty"})
+			break
+		}
+	}
+	if s.Logo != "" && !isLocalArtwork(s.Logo) {
+		if msg := checkStreamURL(s.Logo); msg != "" {
+			problems = append(problems, fieldProblem{"logo", "logo " + msg})
+		}
+	}
+	if s.Homepage != "" {
+		if msg := checkStreamURL(s.Homepage); msg != "" {
+			problems = append(problems, fieldProblem{"homepage", "homepage " + msg})
+		}
+	}
+	for _, problem := range s.Settings.validate() {
+		problems = append(problems, fieldProblem{"settings", problem})
+	}
+	for _, host := range s.Mirrors {
+		if host == "" || strings.ContainsAny(host, "/ ") {
+			problems = append(problems, fieldProblem{"mirrors", fmt.Sprintf("mirror %q must be a bare host name", host)})
+		}
+	}
+	return problems
+}
+
+// checkStreamURL returns a description of what is wrong with a stream URL, or ""
+func checkStreamURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return fmt.Sprintf("%q is not a valid URL", raw)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Sprintf("%q must start with http:// or https://", raw)
+	}
+	if u.Host == "" {
+		return fmt.Sprintf("%q has no host", raw)
+	}
+	return ""
+}
+
+// mergeStations applies the user's file to the built-in stations following the merge policy
+func mergeStations(defaults []RadioStation, file *stationFile, entries []RadioStation) []RadioStation {
+	removed := make(map[string]bool)
+	for _, key := range file.Remove {
+		removed[key] = true
+	}
+
+	var merged []RadioStation
+	index := make(map[string]int)
+	if file.IncludeDefaults == nil || *file.IncludeDefaults {
+		for _, s := range defaults {
+			if removed[s.Key()] {
+				continue
+			}
+			index[s.Key()] = len(merged)
+			merged = append(merged, s)
+		}
+	}
+
+	for _, entry := range entries {
+		if i, ok := index[entry.Key()]; ok {
+			merged[i] = overrideStation(merged[i], entry)
+			continue
+		}
+		index[entry.Key()] = len(merged)
+		merged = append(merged, entry)
+	}
+	return merged
+}
+
+// overrideStation copies the fields set in override onto base
+func overrideStation(base, override RadioStation) RadioStation {
+	if override.ID != "" {
+		base.ID = override.ID
+	}
+	if override.Name != "" {
+		base.Name = override.Name
+	}
+	if override.URL != "" && override.URL != base.URL {
+		base.URL = override.URL
+		base.Streams, base.Mirrors = nil, nil
+	}
+	if override.Genre != "" {
+		base.Genre = override.Genre
+	}
+	if override.Description != "" {
+		base.Description = override.Description
+	}
+	if len(override.Tags) > 0 {
+		base.Tags = override.Tags
+	}
+	if len(override.Streams) > 0 {
+		base.Streams = override.Streams
+	}
+	if len(override.Mirrors) > 0 {
+		base.Mirrors = override.Mirrors
+	}
+	if override.Settings != nil {
+		base.Settings = override.Settings
+	}
+	if override.Logo != "" {
+		base.Logo = override.Logo
+	}
+	if override.Homepage != "" {
+		base.Homepage = override.Homepage
+	}
+	return base
+}
+
+// stationOffsets returns the byte offset of each element of the top-level "stations" array
+func stationOffsets(data []byte) ([]int, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if _, err := dec.Token(); err != nil { // opening {
+		return nil, err
+	}
+
+	for dec.More() {
+		key, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		if key != "stations" {
+			var skip json.RawMessage
+			if err := dec.Decode(&skip); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		if _, err := dec.Token(); err != nil { // opening [
+			return nil, err
+		}
+		var offsets []int
+		for dec.More() {
+			offsets = append(offsets, skipSeparators(data, int(dec.InputOffset())))
+			var skip json.RawMessage
+			if err := dec.Decode(&skip); err != nil {
+				return nil, err
+			}
+		}
+		return offsets, nil
+	}
+	return nil, nil
+}
+
+// skipSeparators advances past whitespace and commas to the start of the next value
+func skipSeparators(data []byte, offset int) int {
+	for offset < len(data) && strings.ContainsRune(" \t\r\n,", rune(data[offset])) {
+		offset++
+	}
+	return offset
+}
+
+// fieldOffset finds where a field's key appears inside a raw JSON object
+func fieldOffset(raw []byte, field string) int {
+	if field == "" {
+		return 0
+	}
+	if i := bytes.Index(raw, []byte(`"`+field+`"`)); i >= 0 {
+		return i
+	}
+	return 0
+}
+
+// jsonErrorOffset extracts the byte offset of a decoding error
+func jsonErrorOffset(err error, data []byte) int {
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.As(err, &syntaxErr):
+		return int(syntaxErr.Offset)
+	case errors.As(err, &typeErr):
+		return int(typeErr.Offset)
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		return len(data)
+	}
+
+	// Unknown field errors carry no offset, so look for the field name instead
+	msg := err.Error()
+	if strings.HasPrefix(msg, "json: unknown field ") {
+		field := strings.Trim(strings.TrimPrefix(msg, "json: unknown field "), `"`)
+		return fieldOffset(data, field)
+	}
+	return 0
+}
+
+// jsonErrorMessage turns a decoding error into a message without the "json:" prefix
+func jsonErrorMessage(err error) string {
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		return fmt.Sprintf("field %q should be %s, not %s", typeErr.Field, typeErr.Type, typeErr.Value)
+	}
+	if errors.Is(err, io.ErrUnexpectedEOF) || err == io.EOF {
+		return "unexpected end of file"
+	}
+	return strings.TrimPrefix(err.Error(), "json: ")
+}
+
+// lineCol converts a byte offset into a 1-based line and column
+func lineCol(data []byte, offset int) (int, int) {
+	if offset > len(data) {
+		offset = len(data)
+	}
+	line, col := 1, 1
+	for _, b := range data[:offset] {
+		if b == '\n' {
+			line++
+			col = 1
+		} else {
+			col++
+		}
+	}
+	return line, col
+}
+
+// savedStationFile is the layout written back to disk when stations are edited in the app
+type savedStationFile struct {
+	Version         int            `json:"version"`
+	IncludeDefaults *bool          `json:"include_defaults,omitempty"`
+	Remove          []string       `json:"remove,omitempty"`
+	Stations        []RadioStation `json:"stations"`
+}
+
+// readStationFileForEdit loads the user's station file so it can be changed and saved.
+// A missing file yields an empty one; an invalid file is refused so it is never clobbered.
+func readStationFileForEdit() (string, *savedStationFile, error) {
+	path, err := stationsPath()
+	if err != nil {
+		return "", nil, err
+	}
+
+	data, err := readStationFile(path)
+	if os.IsNotExist(err) {
+		return path, &savedStationFile{}, nil
+	}
+	if err != nil {
+		return "", nil, err
+	}
+
+	file, entries, err := parseStationFile(path, data, BuiltinStations())
+	if err != nil {
+		return "", nil, fmt.Errorf("fix %s before editing stations in the app:\n%v", filepath.Base(path), err)
+	}
+	return path, &savedStationFile{
+		IncludeDefaults: file.IncludeDefaults,
+		Remove:          file.Remove,
+		Stations:        entries,
+	}, nil
+}
+
+// writeStationFile saves the user's station file atomically
+func writeStationFile(path string, file *savedStationFile) error {
+	file.Version = stationFileVersion
+	data, err := marshalUnescaped(file, "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(path, data, 0644)
+}
+
+// isBuiltinStation reports whether key names one of the compiled-in stations
+func isBuiltinStation(key string) bool {
+	for _, s := range BuiltinStations() {
+		if s.Key() == key {
+			return true
+		}
+	}
+	return false
+}
+
+// AddStation appends a new station to the user's station file
+func AddStation(station RadioStation) error {
+	return AddStations([]RadioStation{station})
+}
+
+// AddStations appends new stations to the user's station file in one write
+func AddStations(added []RadioStation) error {
+	path, file, err := readStationFileForEdit()
+	if err != nil {
+		return err
+	}
+
+	stations, _ := LoadStations()
+	taken := make(map[string]bool)
+	for _, s := range stations {
+		taken[s.Key()] = true
+	}
+	for _, station := range added {
+		if taken[station.Key()] {
+			return fmt.Errorf("a station with key %q already exists", station.Key())
+		}
+		taken[station.Key()] = true
+	}
+
+	file.Stations = append(file.Stations, added...)
+	return writeStationFile(path, file)
+}
+
+// UpdateStation replaces the station stored under key. The key is pinned as the
+// station's id so renaming it keeps favorites and other per-station settings.
+func UpdateStation(key string, station RadioStation) error {
+	path, file, err := readStationFileForEdit()
+	if err != nil {
+		return err
+	}
+
+	station.ID = key
+	for i, s := range file.Stations {
+		if s.Key() == key {
+			file.Stations[i] = station
+			return writeStationFile(path, file)
+		}
+	}
+
+	// Built-in stations get a full override entry; it keeps the built-in position
+	file.Stations = append(file.Stations, station)
+	return writeStationFile(path, file)
+}
+
+// DeleteStation removes the station stored under key, hiding it if it is built in
+func DeleteStation(key string) error {
+	path, file, err := readStationFileForEdit()
+	if err != nil {
+		return err
+	}
+
+	kept := file.Stations[:0]
+	for _, s := range file.Stations {
+		if s.Key() != key {
+			kept = append(kept, s)
+		}
+	}
+	file.Stations = kept
+
+	if isBuiltinStation(key) {
+		file.Remove = append(file.Remove, key)
+	}
+	return writeStationFile(path, file)
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// LogoType represents different logo options
+type LogoType int
+
+const (
+	LogoOriginal LogoType = iota
+	LogoPepe
+	LogoNone
+)
+
+func (l LogoType) String() string {
+	switch l {
+	case LogoOriginal:
+		return "GoRadio Hub"
+	case LogoPepe:
+		return "Pepe"
+	case LogoNone:
+		return "None"
+	default:
+		return "GoRadio Hub"
+	}
+}
+
+// ASCII art for GoRadio logo - Compact and clean
+const GoRadioLogo = `
+  ╔══════════════════════════════════════════════════╗
+  ║           G O R A D I O   H U B                  ║
+  ║         ~ Terminal Music Player v1.0 ~           ║
+  ╚══════════════════════════════════════════════════╝
+`
+
+// ASCII art for Pepe the Frog - Perfectly aligned
+const PepeLogo = `
+      ╔═══════════════════════════════════╗
+      ║          ,-._____.-.              ║
+      ║         /  o     o  \             ║
+      ║        |       >      |           ║
+      ║        |   \_______/   |           ║
+      ║         \             /           ║
+      ║          '-.______.-'             ║
+      ║                                   ║
+      ║   🐸 F E E L S  G O O D  M A N   ║
+      ║        ~ Bringing Da Vibes ~      ║
+      ╚═══════════════════════════════════╝
+`
+
+var (
+	// Logo styles
+	logoStyle = lipgloss.NewStyle().
+			Foreground(primaryColor).
+			Bold(true).
+			Align(lipgloss.Center).
+			Margin(1, 0, 2, 0)
+
+	// Pepe style - bold green
+	pepeStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("#00FF00")).
+			Bold(true).
+			Align(lipgloss.Center).
+			Margin(1, 0, 2, 0)
+)
+
+// RenderLogo renders the selected ASCII logo
+func RenderLogo(logoType LogoType) string {
+	switch logoType {
+	case LogoOriginal:
+		return logoStyle.Render(GoRadioLogo)
+	case LogoPepe:
+		return pepeStyle.Render(PepeLogo)
+	case LogoNone:
+		return ""
+	default:
+		return logoStyle.Render(GoRadioLogo)
+	}
+}
+
+// RenderLogoSelector renders the logo selection info
+func RenderLogoSelector(currentLogo LogoType) string {
+	selectorText := fmt.Sprintf("Logo: %s (Press 'l' to cycle)", currentLogo.String())
+	return lipgloss.NewStyle().
+		Foreground(mutedColor).
+		Italic(true).
+		Align(lipgloss.Center).
+		Margin(0, 0, 1, 0).
+		Render(selectorText)
+}
@@ -1,9 +1,11 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"log"
 	"os"
+	"strings"
 	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
@@ -11,29 +13,135 @@ import (
 
 // Model represents the state of our TUI application
 type Model struct {
-	stations      []RadioStation
-	selected      int
-	startIdx      int
-	visibleCount  int
-	player        *Player
-	showHelp      bool
-	lastUpdate    time.Time
-	animationStep int
-	quitting      bool
-	currentLogo   LogoType
+	stations        []RadioStation
+	selected        int
+	startIdx        int
+	visibleCount    int
+	player          *Player
+	showHelp        bool
+	lastUpdate      time.Time
+	animationStep   int
+	quitting        bool
+	currentLogo     LogoType
+	showDiagnostics bool
+	config          *Config
+	navSeq          int
+	devicePicker    *devicePicker
+	spectrum        []float64
+	statusMsg       string
+	form            *stationForm
+	confirmDelete   *RadioStation
+	prompt          *textPrompt
+	browser         *directoryBrowser
+	list            []RadioStation // Stations shown in the list: m.stations filtered by view
+	view            listView
+	favorites       *Favorites
+	history         *History
+	ratings         Ratings
+	tagFilter       TagFilter
+	health          *healthReport
+	availability    *Availability
+	dedupe          *dedupeView
+	profiles        *Profiles
+	profilePicker   *profilePicker
+	sleepAt         time.Time                // When the sleep timer stops playback; zero when not running
+	artwork         map[string]*artworkEntry // Loaded station logos by source
+	artworkMode     ArtworkMode
+	output          *terminalOutput // The program's output, for escapes sent outside frames
+	width, height   int             // Terminal size, zero until the first resize
+	streamInfo      map[string]*streamInfoEntry // Server headers by station key
+	enrich          *enrichView
 }
 
+// listView selects which stations the list shows
+type listView int
+
+const (
+	viewAll listView = iota
+	viewFavorites
+	viewRecent
+)
+
 // tickMsg is sent every second for animations and updates
 type tickMsg time.Time
 
+// playerEventMsg carries an update from the playback backend
+type playerEventMsg PlayerEvent
+
+// audioDevicesMsg delivers the result of querying mpv for output devices
+type audioDevicesMsg struct {
+	devices []AudioDevice
+	err     error
+}
+
+// spectrumMsg asks the model to pull fresh visualizer bars from the player
+type spectrumMsg time.Time
+
+// warmDwellMsg fires once a station has stayed highlighted for warmDwell
+type warmDwellMsg struct {
+	seq int
+}
+
+// somaFMDueMsg fires when the scheduled SomaFM catalog refresh is due
+type somaFMDueMsg struct{}
+
+// somaFMSyncMsg delivers the outcome of a SomaFM catalog refresh
+type somaFMSyncMsg struct {
+	result SomaFMSyncResult
+	manual bool
+}
+
 // Init initializes the model
 func (m Model) Init() tea.Cmd {
 	return tea.Batch(
 		tick(),
 		tea.EnterAltScreen,
+		waitForPlayerEvent(m.player),
+		spectrumTick(false),
+		scheduleSomaFMSync(m.config, SomaFMCatalogAge()),
+		scheduleMonitor(m.config, m.availability.LastCheck),
 	)
 }
 
+// scheduleSomaFMSync waits until the catalog fetched at lastFetch is due for a refresh
+func scheduleSomaFMSync(cfg *Config, lastFetch time.Time) tea.Cmd {
+	if cfg.SomaFMRefreshHours <= 0 {
+		return nil
+	}
+	wait := time.Until(lastFetch.Add(time.Duration(cfg.SomaFMRefreshHours) * time.Hour))
+	if wait < 0 {
+		wait = 0
+	}
+	return tea.Tick(wait, func(time.Time) tea.Msg {
+		return somaFMDueMsg{}
+	})
+}
+
+// syncSomaFM refreshes the SomaFM catalog in the background
+func syncSomaFM(catalogURL string, manual bool) tea.Cmd {
+	return func() tea.Msg {
+		return somaFMSyncMsg{result: SyncSomaFM(catalogURL), manual: manual}
+	}
+}
+
+// spectrumTick schedules the next visualizer frame; idle players are polled slowly
+func spectrumTick(active bool) tea.Cmd {
+	interval := spectrumFrameInterval
+	if !active {
+		interval = 500 * time.Millisecond
+	}
+	return tea.Tick(interval, func(t time.Time) tea.Msg {
+		return spectrumMsg(t)
+	})
+}
+
+// waitForPlayerEvent blocks until the player reports a backend event
+func waitForPlayerEvent(p *Player) tea.Cmd {
+	return func() tea.Msg {
+		return playerEventMsg(<-p.Events())
+	}
+}
+
 // tick sends a tick message every second
 func tick() tea.Cmd {
 	return tea.Tick(time.Second, func(t time.Time) tea.Msg {
@@ -45,10 +153,39 @@ func tick() tea.Cmd {
 func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
+		if m.devicePicker != nil && msg.String() != "ctrl+c" {
+			return m.updateDevicePicker(msg)
+		}
+		if m.profilePicker != nil && msg.String() != "ctrl+c" {
+			return m.updateProfilePicker(msg)
+		}
+		if m.form != nil && msg.String() != "ctrl+c" {
+			return m.updateForm(msg)
+		}
+		if m.confirmDelete != nil && msg.String() != "ctrl+c" {
+			return m.updateConfirmDelete(msg)
+		}
+		if m.prompt != nil && msg.String() != "ctrl+c" {
+			return m.updatePrompt(msg)
+		}
+		if m.browser != nil && msg.String() != "ctrl+c" {
+			return m.updateBrowser(msg)
+		}
+		if m.health != nil && msg.String() != "ctrl+c" {
+			return m.updateHealthReport(msg)
+		}
+		if m.dedupe != nil && msg.String() != "ctrl+c" {
+			return m.updateDedupe(msg)
+		}
+		if m.enrich != nil && msg.String() != "ctrl+c" {
+			return m.updateEnrich(msg)
+		}
+		
 		switch msg.String() {
 		case "ctrl+c", "q":
 			m.quitting = true
-			m.player.Stop()
+			m.history.Track(nil, false, time.Now())
+			m.player.Close()
 			return m, tea.Quit
 			
 		case "up", "k":
@@ -57,46 +194,330 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				if m.selected < m.startIdx {
 					m.startIdx = m.selected
 				}
+				cmd := m.scheduleWarm()
+				return m, cmd
 			}
 			
 		case "down", "j":
-			if m.selected < len(m.stations)-1 {
+			if m.selected < len(m.list)-1 {
 				m.selected++
 				if m.selected >= m.startIdx+m.visibleCount {
 					m.startIdx = m.selected - m.visibleCount + 1
 				}
+				cmd := m.scheduleWarm()
+				return m, cmd
 			}
 			
 		case "enter", " ":
-			station := &m.stations[m.selected]
-			if m.player.GetCurrentStation() == station && m.player.GetState() == StatePlaying {
+			station := m.selectedStation()
+			if station == nil {
+				break
+			}
+			if current := m.player.GetCurrentStation(); current != nil && current.Key() == station.Key() && m.player.GetState() == StatePlaying {
 				m.player.Stop()
+				m.sleepAt = time.Time{}
+			} else {
+				m.play(station)
+			}
+			
+		case "a":
+			m.form = newStationForm(nil)
+			
+		case "e":
+			if station := m.selectedStation(); station != nil {
+				m.form = newStationForm(station)
+			}
+			
+		case "x":
+			if station := m.selectedStation(); station != nil {
+				copied := *station
+				m.confirmDelete = &copied
+			}
+			
+		case "i":
+			m.prompt = &textPrompt{
+				kind:  promptImport,
+				title: "Import Stations",
+				hint:  "M3U, M3U8, PLS or OPML file; stations already in the library are skipped",
+				input: formField{label: "File"},
+			}
+			
+		case "E":
+			m.prompt = &textPrompt{
+				kind:  promptExport,
+				title: fmt.Sprintf("Export %d Stations", len(m.listStations())),
+				hint:  "Format follows the extension: .m3u, .m3u8, .pls or .opml",
+				input: formField{label: "File", value: []rune("stations.m3u")},
+			}
+			
+		case "/":
+			m.browser = newDirectoryBrowser()
+			
+		case "f":
+			station := m.selectedStation()
+			if station == nil {
+				break
+			}
+			key := station.Key()
+			if m.favorites.Toggle(key) {
+				m.statusMsg = fmt.Sprintf("Added %q to favorites", station.Name)
 			} else {
-				err := m.player.Play(station)
-				if err != nil {
-					// Error is handled in the player
+				m.statusMsg = fmt.Sprintf("Removed %q from favorites", station.Name)
+				if m.view == viewFavorites {
+					key = m.neighborKey(key)
 				}
 			}
+			m.saveFavorites()
+			m.refreshList(key)
+			
+		case "F", "R":
+			key := ""
+			if station := m.selectedStation(); station != nil {
+				key = station.Key()
+			}
+			view := viewFavorites
+			if msg.String() == "R" {
+				view = viewRecent
+			}
+			if m.view == view {
+				view = viewAll
+			}
+			m.view = view
+			m.refreshList(key)
+			
+		case "p":
+			// Last channel: back to whatever played before the current station
+			current := ""
+			if station := m.player.GetCurrentStation(); station != nil {
+				current = station.Key()
+			}
+			previous := m.history.Previous(current)
+			if previous == "" {
+				m.statusMsg = "No previous station"
+				break
+			}
+			for _, entry := range m.history.Entries(m.stations) {
+				if entry.Station.Key() != previous {
+					continue
+				}
+				station := entry.Station
+				m.play(&station)
+				m.selectKey(previous)
+				break
+			}
+			
+		case "K", "J":
+			// Reorder presets, or the active profile; only meaningful in their own order
+			station := m.selectedStation()
+			profile := m.profiles.Current()
+			if station == nil || m.view == viewRecent || (m.view != viewFavorites && profile == nil) {
+				break
+			}
+			if m.config.Sort != SortCustom {
+				m.statusMsg = "Stations can only be rearranged in custom order (press s)"
+				break
+			}
+			delta := -1
+			if msg.String() == "J" {
+				delta = 1
+			}
+			if m.view == viewFavorites {
+				if m.favorites.Move(station.Key(), delta, m.list) {
+					m.saveFavorites()
+					m.refreshList(station.Key())
+				}
+			} else if profile.Move(station.Key(), delta, m.list) {
+				m.saveProfiles()
+				m.refreshList(station.Key())
+			}
+			
+		case "t":
+			m.prompt = &textPrompt{
+				kind:  promptTagFilter,
+				title: "Filter by Tags",
+				hint:  "a+b: both tags   a,b: either tag   empty: show all",
+				input: formField{label: "Tags", value: []rune(m.tagFilter.String())},
+			}
+			
+		case "1", "2", "3", "4", "5":
+			station := m.selectedStation()
+			if station == nil {
+				break
+			}
+			rating := m.ratings.Rate(station.Key(), int(msg.String()[0]-'0'))
+			if err := m.ratings.Save(); err != nil {
+				log.Printf("ratings: saving failed: %v", err)
+				m.statusMsg = "⚠ saving ratings: " + firstLine(err.Error())
+			} else if rating == 0 {
+				m.statusMsg = fmt.Sprintf("Cleared rating for %q", station.Name)
+			} else {
+				m.statusMsg = fmt.Sprintf("Rated %q %d/5", station.Name, rating)
+			}
+			m.refreshList(station.Key())
+			
+		case "s":
+			m.config.Sort = m.config.Sort.Next()
+			m.saveConfig()
+			key := ""
+			if station := m.selectedStation(); station != nil {
+				key = station.Key()
+			}
+			m.refreshList(key)
+			
+		case "H":
+			return m.startHealthCheck()
+			
+		case "D":
+			return m.startDedupe()
+			
+		case "I":
+			return m.startEnrich()
+			
+		case "P":
+			picker := &profilePicker{}
+			for i, profile := range m.profiles.Profiles {
+				if profile.Name == m.profiles.Active {
+					picker.cursor = i + 1
+				}
+			}
+			m.profilePicker = picker
+			
+		case "S":
+			m.statusMsg = "Syncing SomaFM catalog..."
+			return m, syncSomaFM(m.config.SomaFMURL, true)
 			
 		case "l":
 			// Cycle through logo types
 			m.currentLogo = LogoType((int(m.currentLogo) + 1) % 3)
 			
+		case "d":
+			m.showDiagnostics = !m.showDiagnostics
+			
+		case "o":
+			m.devicePicker = &devicePicker{loading: true}
+			return m, listAudioDevices(m.player)
+			
+		case "v":
+			m.config.Visualizer = !m.config.Visualizer
+			m.saveConfig()
+			m.player.SetVisualizer(m.config.Visualizer)
+			
+		case "w":
+			m.config.WarmMode = !m.config.WarmMode
+			m.saveConfig()
+			if !m.config.WarmMode {
+				m.player.DropWarm()
+				return m, nil
+			}
+			cmd := m.scheduleWarm()
+			return m, cmd
+			
+		case "b":
+			m.config.Quality = m.config.Quality.Next()
+			m.saveConfig()
+			m.replayIfQualityChanged()
+			
+		case "B":
+			// Cycle override: none -> high -> low -> data-saver -> none
+			station := m.selectedStation()
+			if station == nil {
+				break
+			}
+			key := station.Key()
+			if q, ok := m.config.StationQuality[key]; !ok {
+				m.config.StationQuality[key] = QualityHigh
+			} else if q.Next() == QualityHigh {
+				delete(m.config.StationQuality, key)
+			} else {
+				m.config.StationQuality[key] = q.Next()
+			}
+			m.saveConfig()
+			m.replayIfQualityChanged()
+			
 		case "?":
 			m.showHelp = !m.showHelp
 		}
 		
+	case playerEventMsg:
+		m.recordPlayingInfo(msg.Stats)
+		return m, waitForPlayerEvent(m.player)
+		
+	case spectrumMsg:
+		bars, ok := m.player.GetSpectrum()
+		m.spectrum = bars
+		return m, spectrumTick(ok)
+		
+	case probeResultMsg:
+		return m.handleProbeResult(ProbeResult(msg))
+		
+	case somaFMDueMsg:
+		return m, syncSomaFM(m.config.SomaFMURL, false)
+		
+	case somaFMSyncMsg:
+		return m.handleSomaFMSync(msg)
+		
+	case healthResultsMsg:
+		return m.handleHealthResults(msg)
+		
+	case artworkMsg:
+		return m.handleArtwork(msg)
+		
+	case artworkUploadedMsg:
+		return m.handleArtworkUploaded(msg)
+		
+	case duplicatesMsg:
+		return m.handleDuplicates(msg)
+		
+	case streamInfoMsg:
+		return m.handleStreamInfo(msg)
+		
+	case monitorDueMsg:
+		return m.startMonitor()
+		
+	case monitorResultsMsg:
+		return m.handleMonitorResults(msg)
+		
+	case directoryResultsMsg:
+		return m.handleDirectoryResults(msg)
+		
+	case audioDevicesMsg:
+		if m.devicePicker != nil {
+			m.devicePicker.loading = false
+			m.devicePicker.devices = msg.devices
+			m.devicePicker.err = msg.err
+		}
+		
+	case warmDwellMsg:
+		// Only warm if the user is still resting on the same station
+		if station := m.selectedStation(); station != nil && m.config.WarmMode && msg.seq == m.navSeq {
+			m.player.Warm(station, m.playOptionsFor(station), m.config.MaxWarm, m.config.WarmCacheKB)
+		}
+		
 	case tickMsg:
 		m.animationStep++
 		m.lastUpdate = time.Time(msg)
-		return m, tick()
+		m.history.Track(m.player.GetCurrentStation(), m.player.GetState() == StatePlaying, m.lastUpdate)
+		m.checkSleepTimer(m.lastUpdate)
+		if m.view == viewRecent {
+			key := ""
+			if station := m.selectedStation(); station != nil {
+				key = station.Key()
+			}
+			m.refreshList(key)
+		}
+		m.player.ExpireWarm(m.config.MaxWarm)
+		return m, tea.Batch(tick(), m.artworkCmd())
 		
 	case tea.WindowSizeMsg:
 		// Adjust visible count based on window height
 		m.visibleCount = max(5, min(20, msg.Height-10))
+		m.width, m.height = msg.Width, msg.Height
+		// The renderer redraws the whole screen, erasing images drawn over it
+		m.output.resetOverlay()
 		
 	case tea.QuitMsg:
-		m.player.Stop()
+		m.history.Track(nil, false, time.Now())
+		m.player.Close()
 		return m, tea.Quit
 	}
 	
@@ -123,18 +544,75 @@ func (m Model) View() string {
 	leftContent += RenderLogoSelector(m.currentLogo)
 	leftContent += "\n"
 	
-	// Add subtitle with animation
-	subtitle := fmt.Sprintf("🎵 Radio Stations %s", WaveAnimation(m.animationStep))
+	// Add subtitle with the live spectrum, or the wave animation when there are no samples
+	visual := WaveAnimation(m.animationStep)
+	if len(m.spectrum) > 0 {
+		visual = RenderSpectrum(m.spectrum)
+	}
+	title := "🎵 Radio Stations"
+	if profile := m.profiles.Current(); profile != nil {
+		title = "🎵 " + profile.Name
+	}
+	switch m.view {
+	case viewFavorites:
+		title = "★ Favorites"
+	case viewRecent:
+		title = "🕘 Recently Played"
+	}
+	if len(m.tagFilter) > 0 {
+		title += " · " + m.tagFilter.String()
+	}
+	if m.config.Sort != SortCustom && m.config.Sort != "" {
+		title += " · by " + m.config.Sort.String()
+	}
+	if !m.sleepAt.IsZero() {
+		title += fmt.Sprintf(" · ⏾ %dm", int(time.Until(m.sleepAt).Round(time.Minute)/time.Minute))
+	}
+	subtitle := fmt.Sprintf("%s %s", title, visual)
 	leftContent += RenderSubtitle(subtitle)
 	leftContent += "\n"
 	
 	// Add station list
-	leftContent += RenderStationList(m.stations, m.selected, m.startIdx, m.visibleCount)
+	if len(m.list) == 0 && len(m.tagFilter) > 0 {
+		leftContent += RenderStatus("No stations match " + m.tagFilter.String() + ": press t to change the filter")
+	} else if len(m.list) == 0 && m.view == viewFavorites {
+		leftContent += RenderStatus("No favorites yet: press f on a station, F to go back")
+	} else if len(m.list) == 0 && m.view == viewRecent {
+		leftContent += RenderStatus("Nothing played yet: R to go back")
+	} else if m.view == viewRecent {
+		leftContent += RenderRecentList(m.list, m.selected, m.startIdx, m.visibleCount, m.lastUpdate)
+	} else {
+		leftContent += RenderStationList(m.list, m.selected, m.startIdx, m.visibleCount)
+	}
 	
 	// Right panel content
 	var rightContent string
+	shownArtwork := ""
 	
-	if m.showHelp {
+	if m.profilePicker != nil {
+		rightContent += RenderProfilePicker(m.profilePicker, m.profiles)
+	} else if m.devicePicker != nil {
+		station := m.selectedStation()
+		override, hasOverride := "", false
+		if station != nil {
+			override, hasOverride = m.config.StationDevices[station.Key()]
+		}
+		rightContent += RenderDevicePicker(m.devicePicker, m.config.AudioDevice, station, override, hasOverride)
+	} else if m.form != nil {
+		rightContent += RenderStationForm(m.form)
+	} else if m.prompt != nil {
+		rightContent += RenderPrompt(m.prompt)
+	} else if m.browser != nil {
+		rightContent += RenderDirectoryBrowser(m.browser)
+	} else if m.health != nil {
+		rightContent += RenderHealthReport(m.health, m.visibleCount)
+	} else if m.dedupe != nil {
+		rightContent += RenderDedupeView(m.dedupe)
+	} else if m.enrich != nil {
+		rightContent += RenderEnrichView(m.enrich)
+	} else if m.confirmDelete != nil {
+		rightContent += RenderConfirm(fmt.Sprintf("Delete station %q?", m.confirmDelete.Name))
+	} else if m.showHelp {
 		rightContent += RenderTitle("Help")
 		rightContent += "\n"
 		rightContent += RenderHelp()
@@ -153,35 +631,91 @@ func (m Model) View() string {
 			song = m.player.GetCurrentSong()
 		}
 		
-		rightContent += RenderNowPlaying(currentStation, song, status)
+		rightContent += RenderNowPlaying(currentStation, m.player.GetCurrentStream(), song, status)
 		rightContent += "\n"
 		
 		// Station details
-		if currentStation != nil {
-			rightContent += RenderStationInfo(currentStation)
-		} else {
+		if m.showDiagnostics {
+			rightContent += RenderDiagnostics(m.player.GetStats(), m.player.GetState())
+		} else if currentStation != nil {
+			if art := m.stationArtwork(currentStation); art != "" {
+				rightContent += art + "\n"
+				shownArtwork = currentStation.Logo
+			}
+			quality, override := m.config.QualityFor(currentStation)
+			rightContent += RenderStationInfo(currentStation, quality, override)
+		} else if selectedStation := m.selectedStation(); selectedStation != nil {
 			// Show info about selected station
-			selectedStation := &m.stations[m.selected]
-			rightContent += RenderStationInfo(selectedStation)
+			if art := m.stationArtwork(selectedStation); art != "" {
+				rightContent += art + "\n"
+				shownArtwork = selectedStation.Logo
+			}
+			quality, override := m.config.QualityFor(selectedStation)
+			rightContent += RenderStationInfo(selectedStation, quality, override)
+			if m.config.WarmMode {
+				rightContent += "\n\n" + RenderWarmStatus(m.player.IsWarm(selectedStation), m.player.WarmCount(), m.config.MaxWarm)
+			}
 		}
 	}
 	
+	// Take down kitty logos that are no longer shown
+	rightContent = m.hiddenArtwork(shownArtwork) + rightContent
+	
 	// Create layout
 	layout := CreateLayout(leftContent, rightContent)
 	
+	if m.statusMsg != "" {
+		layout += "\n" + RenderStatus(m.statusMsg)
+	}
+	
 	// Add help hint at bottom
 	if !m.showHelp {
-		layout += "\n" + RenderStatus("Press ? for help, l to cycle logo, Enter/Space to play/stop, q to quit")
+		layout += "\n" + RenderStatus("Press ? for help, l to cycle logo, d for diagnostics, Enter/Space to play/stop, q to quit")
 	}
 	
-	return layout
+	return m.placeSixel(layout, shownArtwork)
 }
 
 // NewModel creates a new model instance
 func NewModel() Model {
-	stations := GetStations()
+	stations, err := LoadStations()
+	statusMsg := ""
+	if err != nil {
+		log.Printf("stations: %v", err)
+		statusMsg = "⚠ " + firstLine(err.Error()) + " (using built-in stations, see log)"
+	}
+	
+	favorites, err := LoadFavorites()
+	if err != nil {
+		log.Printf("favorites: %v", err)
+		statusMsg = "⚠ favorites: " + firstLine(err.Error())
+	}
+	history, err := LoadHistory()
+	if err != nil {
+		log.Printf("history: %v", err)
+		statusMsg = "⚠ history: " + firstLine(err.Error())
+	}
+	ratings, err := LoadRatings()
+	if err != nil {
+		log.Printf("ratings: %v", err)
+		statusMsg = "⚠ ratings: " + firstLine(err.Error())
+	}
+	availability, err := LoadAvailability()
+	if err != nil {
+		log.Printf("availability: %v", err)
+	}
+	profiles, err := LoadProfiles()
+	if err != nil {
+		log.Printf("profiles: %v", err)
+		statusMsg = "⚠ profiles: " + firstLine(err.Error())
+	}
+	if profiles.Active != "" && profiles.Current() == nil {
+		log.Printf("profiles: active profile %q no longer exists", profiles.Active)
+		profiles.Active = ""
+	}
 	
-	return Model{
+	config := LoadConfig()
+	m := Model{
 		stations:     stations,
 		selected:     0,
 		startIdx:     0,
@@ -190,15 +724,313 @@ func NewModel() Model {
 		showHelp:     false,
 		lastUpdate:   time.Now(),
 		currentLogo:  LogoOriginal, // Start with original GoRadio Hub logo
+		config:       config,
+		statusMsg:    statusMsg,
+		favorites:    favorites,
+		history:      history,
+		ratings:      ratings,
+		availability: availability,
+		profiles:     profiles,
+		artwork:      make(map[string]*artworkEntry),
+		artworkMode:  config.Artwork.resolve(),
+		output:       &terminalOutput{File: os.Stdout},
+		streamInfo:   make(map[string]*streamInfoEntry),
+	}
+	m.refreshList("")
+	return m
+}
+
+// selectedStation returns the highlighted station, or nil when the list is empty
+func (m Model) selectedStation() *RadioStation {
+	if m.selected < 0 || m.selected >= len(m.list) {
+		return nil
+	}
+	return &m.list[m.selected]
+}
+
+// listStations returns the stations currently shown in the list
+func (m Model) listStations() []RadioStation {
+	return m.list
+}
+
+// refreshList rebuilds the visible list for the current view and keeps the
+// highlight on selectKey when it is still shown
+func (m *Model) refreshList(selectKey string) {
+	entries := m.history.Entries(m.stations)
+	played := make(map[string]HistoryEntry, len(entries))
+	for _, e := range entries {
+		played[e.Station.Key()] = e
+	}
+	annotate := func(s *RadioStation) {
+		s.Favorite = m.favorites.Has(s.Key())
+		s.Rating = m.ratings[s.Key()]
+		e := played[s.Key()]
+		s.LastPlayed, s.Listened = e.LastPlayed, e.Listened
+		s.Health = m.availability.Latest(s.Key())
+		s.Uptime, s.Checks = m.availability.Uptime(s.Key(), m.lastUpdate)
+	}
+	for i := range m.stations {
+		annotate(&m.stations[i])
+	}
+	
+	// A profile narrows the library to its own stations, in its own order
+	library := m.stations
+	if profile := m.profiles.Current(); profile != nil {
+		library = profile.Filter(m.stations)
+	}
+	
+	switch m.view {
+	case viewFavorites:
+		m.list = m.favorites.Filter(library)
+	case viewRecent:
+		m.list = nil
+		for _, e := range entries {
+			station := e.Station
+			annotate(&station)
+			m.list = append(m.list, station)
+		}
+	default:
+		// Copy so sorting the view leaves the library order alone
+		m.list = append([]RadioStation(nil), library...)
+	}
+	m.list = m.tagFilter.Apply(m.list)
+	SortStations(m.list, m.config.Sort)
+	
+	m.selected = 0
+	m.selectKey(selectKey)
+	if m.selected < m.startIdx || m.selected >= m.startIdx+m.visibleCount {
+		m.startIdx = max(0, m.selected-m.visibleCount/2)
+	}
+}
+
+// selectKey moves the highlight to the station with key, if it is in the list
+func (m *Model) selectKey(key string) bool {
+	for i := range m.list {
+		if m.list[i].Key() != key {
+			continue
+		}
+		m.selected = i
+		if m.selected < m.startIdx || m.selected >= m.startIdx+m.visibleCount {
+			m.startIdx = max(0, m.selected-m.visibleCount/2)
+		}
+		return true
+	}
+	return false
+}
+
+// neighborKey returns the key of the station next to key in the list, so the
+// highlight has somewhere to go when key disappears from it
+func (m Model) neighborKey(key string) string {
+	for i := range m.list {
+		if m.list[i].Key() != key {
+			continue
+		}
+		if i+1 < len(m.list) {
+			return m.list[i+1].Key()
+		}
+		if i > 0 {
+			return m.list[i-1].Key()
+		}
+	}
+	return ""
+}
+
+// saveFavorites persists the favorites, reporting failures in the status line
+func (m *Model) saveFavorites() {
+	if err := m.favorites.Save(); err != nil {
+		log.Printf("favorites: saving failed: %v", err)
+		m.statusMsg = "⚠ saving favorites: " + firstLine(err.Error())
+	}
+}
+
+// reloadStations re-reads the station library and keeps the highlight on selectKey
+func (m *Model) reloadStations(selectKey string) {
+	stations, err := LoadStations()
+	if err != nil {
+		log.Printf("stations: %v", err)
+		m.statusMsg = "⚠ " + firstLine(err.Error())
+	}
+	m.stations = stations
+	m.refreshList(selectKey)
+}
+
+// handleSomaFMSync reloads the library after a catalog refresh. Scheduled
+// refreshes stay quiet unless they fail; the next one is queued either way.
+func (m Model) handleSomaFMSync(msg somaFMSyncMsg) (tea.Model, tea.Cmd) {
+	result := msg.result
+	if !result.Offline && !result.Unchanged {
+		key := ""
+		if station := m.selectedStation(); station != nil {
+			key = station.Key()
+		}
+		m.reloadStations(key)
+	}
+	if msg.manual || result.Offline {
+		m.statusMsg = result.String()
+		if result.Offline {
+			m.statusMsg = "⚠ " + m.statusMsg
+		}
+	}
+	if msg.manual {
+		return m, nil
+	}
+
+	// After a failure, try again on the next interval rather than right away
+	return m, scheduleSomaFMSync(m.config, time.Now())
+}
+
+// firstLine returns the first line of a possibly multi-line message
+func firstLine(s string) string {
+	if i := strings.IndexByte(s, '\n'); i >= 0 {
+		return s[:i]
+	}
+	return s
+}
+
+// saveConfig persists preferences, logging rather than interrupting the UI on failure
+func (m Model) saveConfig() {
+	if err := m.config.Save(); err != nil {
+		log.Printf("config: saving failed: %v", err)
+	}
+}
+
+// listAudioDevices queries the backend for output devices without blocking the UI
+func listAudioDevices(p *Player) tea.Cmd {
+	return func() tea.Msg {
+		devices, err := p.ListAudioDevices()
+		return audioDevicesMsg{devices: devices, err: err}
+	}
+}
+
+// updateDevicePicker handles keys while the output device picker is open
+func (m Model) updateDevicePicker(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	picker := m.devicePicker
+	station := m.selectedStation()
+	
+	switch msg.String() {
+	case "esc", "o", "q":
+		m.devicePicker = nil
+		return m, nil
+		
+	case "up", "k":
+		if picker.cursor > 0 {
+			picker.cursor--
+		}
+		
+	case "down", "j":
+		if picker.cursor < len(picker.devices)-1 {
+			picker.cursor++
+		}
+		
+	case "enter", " ":
+		if picker.cursor < len(picker.devices) {
+			m.config.AudioDevice = picker.devices[picker.cursor].Name
+			m.saveConfig()
+			m.applyAudioDevice()
+		}
+		
+	case "s":
+		if station != nil && picker.cursor < len(picker.devices) {
+			m.config.StationDevices[station.Key()] = picker.devices[picker.cursor].Name
+			m.saveConfig()
+			m.applyAudioDevice()
+		}
+		
+	case "c":
+		if station == nil {
+			break
+		}
+		delete(m.config.StationDevices, station.Key())
+		m.saveConfig()
+		m.applyAudioDevice()
+	}
+	
+	return m, nil
+}
+
+// playOptionsFor gathers the playback preferences for a station: the
+// config's, with the active profile's volume and device filled in
+func (m Model) playOptionsFor(station *RadioStation) PlayOptions {
+	opts := m.config.PlayOptionsFor(station)
+	if profile := m.profiles.Current(); profile != nil {
+		opts.Volume = profile.Volume
+		if _, override := m.config.DeviceFor(station); !override && profile.AudioDevice != "" {
+			opts.AudioDevice = profile.AudioDevice
+		}
+	}
+	return opts
+}
+
+// play starts a station with its preferences and restarts the profile's sleep timer
+func (m *Model) play(station *RadioStation) {
+	// Errors are reported by the player
+	m.player.Play(station, m.playOptionsFor(station))
+	m.armSleepTimer()
+}
+
+// applyAudioDevice moves the playing stream to its effective output device
+func (m Model) applyAudioDevice() {
+	station := m.player.GetCurrentStation()
+	if station == nil {
+		return
+	}
+	device := m.playOptionsFor(station).AudioDevice
+	if err := m.player.SetAudioDevice(device); err != nil {
+		log.Printf("audio device: switching to %q failed: %v", device, err)
+	}
+}
+
+// scheduleWarm starts the dwell timer for the highlighted station when warm mode is on
+func (m *Model) scheduleWarm() tea.Cmd {
+	m.navSeq++
+	if !m.config.WarmMode {
+		return nil
+	}
+	seq := m.navSeq
+	return tea.Tick(warmDwell, func(time.Time) tea.Msg {
+		return warmDwellMsg{seq: seq}
+	})
+}
+
+// replayIfQualityChanged switches the playing station to a new variant after a quality change
+func (m Model) replayIfQualityChanged() {
+	station := m.player.GetCurrentStation()
+	if station == nil || m.player.GetState() == StateStopped {
+		return
+	}
+	opts := m.playOptionsFor(station)
+	if SelectStream(station, opts.Quality).URL != m.player.GetCurrentStream().URL {
+		m.player.Play(station, opts)
 	}
 }
 
 func main() {
+	logFile := flag.String("log-file", "", "write the debug log to this file (default $XDG_STATE_HOME/goradio-hub/goradio.log)")
+	profile := flag.String("profile", "", "start with this station profile instead of the last one used")
+	flag.Parse()
+	
+	if err := setupLogging(*logFile); err != nil {
+		fmt.Fprintf(os.Stderr, "goradio: %v\n", err)
+		os.Exit(1)
+	}
+	
+	// Subcommands run without the TUI
+	if flag.NArg() > 0 {
+		os.Exit(runCLI(flag.Args()))
+	}
+	
 	// Create the model
 	m := NewModel()
+	if *profile != "" {
+		if err := m.profiles.Use(*profile); err != nil {
+			fmt.Fprintf(os.Stderr, "goradio: %v\n", err)
+			os.Exit(1)
+		}
+		m.refreshList("")
+	}
 	
 	// Create the program
-	p := tea.NewProgram(m, tea.WithAltScreen())
+	p := tea.NewProgram(m, tea.WithAltScreen(), tea.WithOutput(m.output))
 	
 	// Run the program
 	if _, err := p.Run(); err != nil {
@@ -228,14 +1060,25 @@ const (
 	AppName = "GoRadio Hub"
 )
 
-// init function to set up the application
-func init() {
-	// Set up logging to a file for debugging
-	f, err := os.OpenFile("goradio.log", os.O_RDWR|os.O_CREATE|os.O_APPEND, 0666)
+// setupLogging sends the debug log to path, or to the state directory when path is empty
+func setupLogging(path string) error {
+	if path == "" {
+		var err error
+		path, err = DefaultLogPath()
+		if err != nil {
+			return fmt.Errorf("finding log directory: %v", err)
+		}
+	}
+	if err := ensureParentDir(path); err != nil {
+		return fmt.Errorf("creating log directory: %v", err)
+	}
+	
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
 	if err != nil {
-		log.Fatalf("error opening file: %v", err)
+		return fmt.Errorf("error opening log file: %v", err)
 	}
 	log.SetOutput(f)
 	
 	log.Printf("Starting %s v%s", AppName, Version)
+	return nil
 }
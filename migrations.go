@@ -0,0 +1,222 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// stationFileVersion is the layout of stations.json this build writes. Files
+// without a "version" field predate versioning and count as version 1.
+//
+// To change the layout: bump stationFileVersion, append a stationMigration
+// from the previous version, and add golden files for it to testdata/migrations
+// (see test_migrations.go).
+const stationFileVersion = 2
+
+// stationMigration upgrades a station file from one version to the next
+type stationMigration struct {
+	from     int
+	describe string
+	apply    func(file *jsonObject) error
+}
+
+// stationMigrations run in order, each taking the file one version further
+var stationMigrations = []stationMigration{
+	{from: 1, describe: "add the version field", apply: stampVersion},
+}
+
+// stampVersion is the step for a version that only adds the "version" field,
+// which MigrateStationFile writes after every step. The stations themselves
+// are kept exactly as they are.
+func stampVersion(file *jsonObject) error {
+	return nil
+}
+
+// stationFileVersionOf returns the version a station file declares, 1 when it
+// has none, or false when the file is not a JSON object
+func stationFileVersionOf(data []byte) (int, bool) {
+	var header struct {
+		Version int `json:"version"`
+	}
+	if err := json.Unmarshal(data, &header); err != nil {
+		return 0, false
+	}
+	if header.Version < 1 {
+		return 1, true
+	}
+	return header.Version, true
+}
+
+// MigrateStationFile upgrades a station file to version target, returning the
+// upgraded file and the version it started at. Files already at target and
+// files too broken to read are returned unchanged; parseStationFile reports
+// the latter. Files from a newer build are refused rather than guessed at.
+func MigrateStationFile(data []byte, target int) ([]byte, int, error) {
+	from, ok := stationFileVersionOf(data)
+	if !ok || from >= target {
+		if from > stationFileVersion {
+			return data, from, fmt.Errorf("written by a newer goradio (version %d, this one reads up to %d)", from, stationFileVersion)
+		}
+		return data, from, nil
+	}
+
+	var file jsonObject
+	if err := json.Unmarshal(data, &file); err != nil {
+		return data, from, nil
+	}
+	for _, step := range stationMigrations {
+		if step.from < from || step.from >= target {
+			continue
+		}
+		if err := step.apply(&file); err != nil {
+			return data, from, fmt.Errorf("upgrading from version %d (%s): %v", step.from, step.describe, err)
+		}
+	}
+	if err := file.setFirst("version", target); err != nil {
+		return data, from, err
+	}
+
+	out, err := marshalUnescaped(file, "  ")
+	if err != nil {
+		return data, from, err
+	}
+	return out, from, nil
+}
+
+// marshalUnescaped encodes v without escaping &, < and >, which are common in
+// station names and genres ("Drum & Bass"). Indented output ends in a newline.
+func marshalUnescaped(v interface{}, indent string) ([]byte, error) {
+	var b bytes.Buffer
+	enc := json.NewEncoder(&b)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", indent)
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	if indent == "" {
+		return bytes.TrimSuffix(b.Bytes(), []byte("\n")), nil
+	}
+	return b.Bytes(), nil
+}
+
+// jsonObject is a JSON object that keeps its keys in file order, so migrated
+// files still look like the ones the user wrote
+type jsonObject struct {
+	keys   []string
+	values map[string]json.RawMessage
+}
+
+// UnmarshalJSON reads an object, remembering the order of its keys
+func (o *jsonObject) UnmarshalJSON(data []byte) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if tok, err := dec.Token(); err != nil {
+		return err
+	} else if tok != json.Delim('{') {
+		return fmt.Errorf("expected an object, found %v", tok)
+	}
+
+	o.keys, o.values = nil, make(map[string]json.RawMessage)
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		key := tok.(string)
+		var value json.RawMessage
+		if err := dec.Decode(&value); err != nil {
+			return err
+		}
+		if _, seen := o.values[key]; !seen {
+			o.keys = append(o.keys, key)
+		}
+		o.values[key] = value
+	}
+	_, err := dec.Token()
+	return err
+}
+
+// MarshalJSON writes the object with its keys in their original order
+func (o jsonObject) MarshalJSON() ([]byte, error) {
+	var b bytes.Buffer
+	b.WriteByte('{')
+	for i, key := range o.keys {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		name, err := marshalUnescaped(key, "")
+		if err != nil {
+			return nil, err
+		}
+		b.Write(name)
+		b.WriteByte(':')
+		b.Write(o.values[key])
+	}
+	b.WriteByte('}')
+	return b.Bytes(), nil
+}
+
+// get decodes the value of key into v, reporting whether the key is present
+func (o *jsonObject) get(key string, v interface{}) (bool, error) {
+	raw, ok := o.values[key]
+	if !ok {
+		return false, nil
+	}
+	if err := json.Unmarshal(raw, v); err != nil {
+		return true, fmt.Errorf("%q: %v", key, err)
+	}
+	return true, nil
+}
+
+// set stores v under key, appending the key if it is new
+func (o *jsonObject) set(key string, v interface{}) error {
+	raw, err := marshalUnescaped(v, "")
+	if err != nil {
+		return err
+	}
+	if o.values == nil {
+		o.values = make(map[string]json.RawMessage)
+	}
+	if _, ok := o.values[key]; !ok {
+		o.keys = append(o.keys, key)
+	}
+	o.values[key] = raw
+	return nil
+}
+
+// setFirst stores v under key and moves the key to the front
+func (o *jsonObject) setFirst(key string, v interface{}) error {
+	if err := o.set(key, v); err != nil {
+		return err
+	}
+	keys := []string{key}
+	for _, k := range o.keys {
+		if k != key {
+			keys = append(keys, k)
+		}
+	}
+	o.keys = keys
+	return nil
+}
+
+// setAfter stores v under key; a new key goes right after the key named after
+func (o *jsonObject) setAfter(key, after string, v interface{}) error {
+	_, existed := o.values[key]
+	if err := o.set(key, v); err != nil || existed {
+		return err
+	}
+	var keys []string
+	for _, k := range o.keys {
+		if k != key {
+			keys = append(keys, k)
+		}
+		if k == after {
+			keys = append(keys, key)
+		}
+	}
+	if len(keys) < len(o.keys) {
+		keys = append(keys, key) // after is missing
+	}
+	o.keys = keys
+	return nil
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"net/url"
+	"sort"
+	"sync"
+	"time"
+)
+
+// mirrorFailureMemory is how long a failed mirror stays deprioritized
+const mirrorFailureMemory = 15 * time.Minute
+
+// somaFMMirrors are the SomaFM relay servers carrying the same streams as ice1
+var somaFMMirrors = []string{"ice2.somafm.com", "ice4.somafm.com", "ice6.somafm.com"}
+
+// mirrorFailure records recent trouble with a single server
+type mirrorFailure struct {
+	Count int
+	Last  time.Time
+}
+
+// MirrorHealth remembers which servers failed recently so they are tried last
+type MirrorHealth struct {
+	mu       sync.Mutex
+	failures map[string]mirrorFailure
+}
+
+// NewMirrorHealth creates an empty mirror health tracker
+func NewMirrorHealth() *MirrorHealth {
+	return &MirrorHealth{failures: make(map[string]mirrorFailure)}
+}
+
+// RecordFailure marks the server behind streamURL as having failed just now
+func (h *MirrorHealth) RecordFailure(streamURL string) {
+	host := hostOf(streamURL)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	f := h.failures[host]
+	f.Count++
+	f.Last = time.Now()
+	h.failures[host] = f
+}
+
+// RecordSuccess clears the failure history of the server behind streamURL
+func (h *MirrorHealth) RecordSuccess(streamURL string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.failures, hostOf(streamURL))
+}
+
+// recentFailures returns the failure count for a host, ignoring stale failures
+func (h *MirrorHealth) recentFailures(host string) (int, time.Time) {
+	f, ok := h.failures[host]
+	if !ok || time.Since(f.Last) > mirrorFailureMemory {
+		return 0, time.Time{}
+	}
+	return f.Count, f.Last
+}
+
+// Order sorts candidate URLs so servers without recent failures come first.
+// The declared order is kept among servers with equal failure history.
+func (h *MirrorHealth) Order(candidates []string) []string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	ordered := append([]string(nil), candidates...)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		ci, li := h.recentFailures(hostOf(ordered[i]))
+		cj, lj := h.recentFailures(hostOf(ordered[j]))
+		if ci != cj {
+			return ci < cj
+		}
+		// Among equally bad mirrors, the one that failed longest ago goes first
+		return li.Before(lj)
+	})
+	return ordered
+}
+
+// mirrorCandidates expands a stream URL into the same path on every declared mirror host
+func mirrorCandidates(streamURL string, mirrors []string) []string {
+	candidates := []string{streamURL}
+
+	u, err := url.Parse(streamURL)
+	if err != nil {
+		return candidates
+	}
+
+	for _, host := range mirrors {
+		if host == "" || host == u.Host {
+			continue
+		}
+		m := *u
+		m.Host = host
+		candidates = append(candidates, m.String())
+	}
+	return candidates
+}
+
+// hostOf returns the host part of a URL, or the URL itself if it cannot be parsed
+func hostOf(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return rawURL
+	}
+	return u.Host
+}
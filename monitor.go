@@ -0,0 +1,238 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+const (
+	// defaultMonitorInterval is how often watched stations are probed in the background
+	defaultMonitorInterval = 30 * time.Minute
+	// monitorStartDelay keeps the first background check out of the way of startup
+	monitorStartDelay = time.Minute
+	// monitorParallel keeps background checks gentle on the network
+	monitorParallel = 2
+	// monitorRecent is how many recently played stations are watched besides favorites
+	monitorRecent = 10
+	// uptimeWindow is how far back uptime is computed; older samples are dropped
+	uptimeWindow = 7 * 24 * time.Hour
+)
+
+// AvailabilitySample is one check of a station
+type AvailabilitySample struct {
+	At     time.Time    `json:"at"`
+	Status HealthStatus `json:"status"`
+}
+
+// Availability keeps the last week of check results per station key
+type Availability struct {
+	Stations  map[string][]AvailabilitySample `json:"stations"`
+	LastCheck time.Time                       `json:"last_check"` // Last background run
+}
+
+// availabilityPath returns the location of the availability log
+func availabilityPath() (string, error) {
+	dir, err := StateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "availability.json"), nil
+}
+
+// LoadAvailability reads the availability log, returning an empty one if it does not exist
+func LoadAvailability() (*Availability, error) {
+	a := &Availability{Stations: make(map[string][]AvailabilitySample)}
+
+	path, err := availabilityPath()
+	if err != nil {
+		return a, err
+	}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return a, nil
+	}
+	if err != nil {
+		return a, err
+	}
+	if err := json.Unmarshal(data, a); err != nil {
+		return &Availability{Stations: make(map[string][]AvailabilitySample)}, err
+	}
+	if a.Stations == nil {
+		a.Stations = make(map[string][]AvailabilitySample)
+	}
+	return a, nil
+}
+
+// Save writes the availability log
+func (a *Availability) Save() error {
+	path, err := availabilityPath()
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(a, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(path, append(data, '\n'), 0644)
+}
+
+// Record adds check results and forgets samples older than the uptime window
+func (a *Availability) Record(results []HealthResult, now time.Time) {
+	for _, r := range results {
+		a.Stations[r.Key] = append(a.Stations[r.Key], AvailabilitySample{At: r.CheckedAt, Status: r.Status})
+	}
+	cutoff := now.Add(-uptimeWindow)
+	for key, samples := range a.Stations {
+		i := 0
+		for i < len(samples) && samples[i].At.Before(cutoff) {
+			i++
+		}
+		if i == len(samples) {
+			delete(a.Stations, key)
+		} else {
+			a.Stations[key] = samples[i:]
+		}
+	}
+}
+
+// Latest returns the most recent status of a station, or "" if it was never checked
+func (a *Availability) Latest(key string) HealthStatus {
+	samples := a.Stations[key]
+	if len(samples) == 0 {
+		return ""
+	}
+	return samples[len(samples)-1].Status
+}
+
+// Uptime returns the share of checks in the last week that found the station
+// playing, degraded included, and how many checks that is based on
+func (a *Availability) Uptime(key string, now time.Time) (float64, int) {
+	cutoff := now.Add(-uptimeWindow)
+	up, total := 0, 0
+	for _, s := range a.Stations[key] {
+		if s.At.Before(cutoff) {
+			continue
+		}
+		total++
+		if s.Status != HealthDead {
+			up++
+		}
+	}
+	if total == 0 {
+		return 0, 0
+	}
+	return float64(up) / float64(total), total
+}
+
+// availabilityLabel words a status the way the station list means it
+func availabilityLabel(status HealthStatus) string {
+	switch status {
+	case HealthOK:
+		return "online"
+	case HealthDegraded:
+		return "degraded"
+	case HealthDead:
+		return "offline"
+	}
+	return "unknown"
+}
+
+// RenderAvailability describes a station's last status and weekly uptime, e.g. "● online · 96% uptime (12 checks)"
+func RenderAvailability(status HealthStatus, uptime float64, checks int) string {
+	line := RenderHealthBadge(status) + " " + availabilityLabel(status)
+	if checks > 0 {
+		plural := "s"
+		if checks == 1 {
+			plural = ""
+		}
+		line += fmt.Sprintf(" · %.0f%% uptime this week (%d check%s)", uptime*100, checks, plural)
+	}
+	return line
+}
+
+// monitorDueMsg fires when the next background availability check is due
+type monitorDueMsg struct{}
+
+// monitorResultsMsg delivers a finished background availability check
+type monitorResultsMsg []HealthResult
+
+// scheduleMonitor waits until a background check is due after the one at lastCheck
+func scheduleMonitor(cfg *Config, lastCheck time.Time) tea.Cmd {
+	if cfg.MonitorMinutes <= 0 {
+		return nil
+	}
+	wait := time.Until(lastCheck.Add(time.Duration(cfg.MonitorMinutes) * time.Minute))
+	if wait < monitorStartDelay {
+		wait = monitorStartDelay
+	}
+	return tea.Tick(wait, func(time.Time) tea.Msg {
+		return monitorDueMsg{}
+	})
+}
+
+// monitorStations checks stations in the background, a couple at a time
+func monitorStations(stations []RadioStation) tea.Cmd {
+	return func() tea.Msg {
+		return monitorResultsMsg(CheckStations(stations, monitorParallel, defaultCheckTimeout))
+	}
+}
+
+// monitorTargets returns the stations the background monitor watches:
+// favorites, then the most recently played ones
+func (m Model) monitorTargets() []RadioStation {
+	seen := make(map[string]bool)
+	var targets []RadioStation
+	for _, station := range m.favorites.Filter(m.stations) {
+		seen[station.Key()] = true
+		targets = append(targets, station)
+	}
+	recent := 0
+	for _, entry := range m.history.Entries(m.stations) {
+		if recent == monitorRecent {
+			break
+		}
+		recent++
+		if key := entry.Station.Key(); !seen[key] {
+			seen[key] = true
+			targets = append(targets, entry.Station)
+		}
+	}
+	return targets
+}
+
+// startMonitor runs a background check of the watched stations
+func (m Model) startMonitor() (tea.Model, tea.Cmd) {
+	targets := m.monitorTargets()
+	if len(targets) == 0 {
+		m.availability.LastCheck = time.Now()
+		return m, scheduleMonitor(m.config, m.availability.LastCheck)
+	}
+	return m, monitorStations(targets)
+}
+
+// recordAvailability stores check results and refreshes the badges in the list
+func (m *Model) recordAvailability(results []HealthResult) {
+	m.availability.Record(results, time.Now())
+	m.recordStreamInfo(results)
+	if err := m.availability.Save(); err != nil {
+		log.Printf("availability: saving failed: %v", err)
+	}
+	key := ""
+	if station := m.selectedStation(); station != nil {
+		key = station.Key()
+	}
+	m.refreshList(key)
+}
+
+// handleMonitorResults records a background check and queues the next one
+func (m Model) handleMonitorResults(results []HealthResult) (tea.Model, tea.Cmd) {
+	m.availability.LastCheck = time.Now()
+	m.recordAvailability(results)
+	return m, scheduleMonitor(m.config, m.availability.LastCheck)
+}
@@ -0,0 +1,259 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// mpvEvent is a single asynchronous message received over mpv's JSON IPC
+type mpvEvent struct {
+	Event string          `json:"event"`
+	ID    int             `json:"id"`
+	Name  string          `json:"name"`
+	Data  json.RawMessage `json:"data"`
+	// Reason is set for end-file events (eof, error, quit, ...)
+	Reason string `json:"reason"`
+}
+
+// mpvResponse is the reply to a command sent over mpv's JSON IPC
+type mpvResponse struct {
+	RequestID int             `json:"request_id"`
+	Error     string          `json:"error"`
+	Data      json.RawMessage `json:"data"`
+}
+
+// mpvProcess wraps a running mpv instance and its JSON IPC connection
+type mpvProcess struct {
+	cmd    *exec.Cmd
+	url    string
+	socket string
+	conn   net.Conn
+
+	writeMu sync.Mutex
+	mu      sync.Mutex
+	nextID  int
+	pending map[int]chan mpvResponse
+
+	events chan mpvEvent
+	done   chan struct{}
+	err    error
+}
+
+var mpvSocketSeq int64
+
+// mpvSocketPath returns a unique IPC socket path for a new mpv instance
+func mpvSocketPath() string {
+	n := atomic.AddInt64(&mpvSocketSeq, 1)
+	return filepath.Join(RuntimeDir(), fmt.Sprintf("goradio-mpv-%d-%d.sock", os.Getpid(), n))
+}
+
+// startMPVProcess launches mpv for the given URL with an IPC server attached.
+// Extra arguments are passed to mpv before the URL.
+func startMPVProcess(url string, extraArgs ...string) (*mpvProcess, error) {
+	socket := mpvSocketPath()
+	os.Remove(socket)
+
+	args := []string{"--no-video", "--no-terminal", "--really-quiet", "--input-ipc-server=" + socket}
+	args = append(args, extraArgs...)
+	if url != "" {
+		args = append(args, url)
+	}
+
+	cmd := exec.Command("mpv", args...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start mpv: %v (make sure mpv is installed)", err)
+	}
+
+	m := &mpvProcess{
+		cmd:     cmd,
+		url:     url,
+		socket:  socket,
+		pending: make(map[int]chan mpvResponse),
+		events:  make(chan mpvEvent, 64),
+		done:    make(chan struct{}),
+	}
+
+	go func() {
+		m.err = cmd.Wait()
+		close(m.done)
+		os.Remove(socket)
+	}()
+
+	return m, nil
+}
+
+// connect dials the IPC socket, retrying until mpv has created it
+func (m *mpvProcess) connect(timeout time.Duration) error {
+	if m.conn != nil {
+		return nil
+	}
+	deadline := time.Now().Add(timeout)
+	for {
+		conn, err := net.Dial("unix", m.socket)
+		if err == nil {
+			m.conn = conn
+			go m.readLoop()
+			return nil
+		}
+
+		select {
+		case <-m.done:
+			return fmt.Errorf("mpv exited before IPC was ready")
+		default:
+		}
+
+		if time.Now().After(deadline) {
+			return fmt.Errorf("mpv IPC not available: %v", err)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+// readLoop dispatches IPC replies to waiting commands and events to the events channel
+func (m *mpvProcess) readLoop() {
+	defer close(m.events)
+
+	scanner := bufio.NewScanner(m.conn)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+
+		var probe struct {
+			Event     string `json:"event"`
+			RequestID *int   `json:"request_id"`
+		}
+		if err := json.Unmarshal(line, &probe); err != nil {
+			continue
+		}
+
+		if probe.Event == "" && probe.RequestID != nil {
+			var resp mpvResponse
+			if err := json.Unmarshal(line, &resp); err != nil {
+				continue
+			}
+			m.mu.Lock()
+			ch := m.pending[resp.RequestID]
+			delete(m.pending, resp.RequestID)
+			m.mu.Unlock()
+			if ch != nil {
+				ch <- resp
+			}
+			continue
+		}
+
+		var ev mpvEvent
+		if err := json.Unmarshal(line, &ev); err != nil {
+			continue
+		}
+		select {
+		case m.events <- ev:
+		default:
+			// Drop events if nobody is keeping up; property changes are resent anyway
+		}
+	}
+
+	// Fail any commands still waiting for a reply
+	m.mu.Lock()
+	for id, ch := range m.pending {
+		ch <- mpvResponse{RequestID: id, Error: "connection closed"}
+		delete(m.pending, id)
+	}
+	m.mu.Unlock()
+}
+
+// command sends an IPC command and waits for its reply
+func (m *mpvProcess) command(args ...interface{}) (json.RawMessage, error) {
+	if m.conn == nil {
+		return nil, fmt.Errorf("mpv IPC not connected")
+	}
+
+	m.mu.Lock()
+	m.nextID++
+	id := m.nextID
+	ch := make(chan mpvResponse, 1)
+	m.pending[id] = ch
+	m.mu.Unlock()
+
+	payload, err := json.Marshal(map[string]interface{}{
+		"command":    args,
+		"request_id": id,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	m.writeMu.Lock()
+	_, err = m.conn.Write(append(payload, '\n'))
+	m.writeMu.Unlock()
+	if err != nil {
+		m.mu.Lock()
+		delete(m.pending, id)
+		m.mu.Unlock()
+		return nil, err
+	}
+
+	select {
+	case resp := <-ch:
+		if resp.Error != "" && resp.Error != "success" {
+			return nil, fmt.Errorf("mpv: %s", resp.Error)
+		}
+		return resp.Data, nil
+	case <-time.After(5 * time.Second):
+		m.mu.Lock()
+		delete(m.pending, id)
+		m.mu.Unlock()
+		return nil, fmt.Errorf("mpv: command %v timed out", args[0])
+	}
+}
+
+// observe asks mpv to send property-change events for a property
+func (m *mpvProcess) observe(id int, name string) error {
+	_, err := m.command("observe_property", id, name)
+	return err
+}
+
+// setProperty sets an mpv property at runtime
+func (m *mpvProcess) setProperty(name string, value interface{}) error {
+	_, err := m.command("set_property", name, value)
+	return err
+}
+
+// getProperty reads an mpv property and decodes it into v
+func (m *mpvProcess) getProperty(name string, v interface{}) error {
+	data, err := m.command("get_property", name)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// kill terminates mpv and closes the IPC connection
+func (m *mpvProcess) kill() {
+	if m.conn != nil {
+		m.conn.Close()
+	}
+	if m.cmd.Process != nil {
+		m.cmd.Process.Kill()
+	}
+}
+
+// exited reports whether the mpv process has terminated
+func (m *mpvProcess) exited() bool {
+	select {
+	case <-m.done:
+		return true
+	default:
+		return false
+	}
+}
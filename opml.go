@@ -0,0 +1,224 @@
+package main
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// OPML layout used for export; import also accepts other apps' variations:
+//
+//	<opml version="2.0">
+//	  <body>
+//	    <outline text="Ambient">
+//	      <outline type="audio" text="Drone Zone" URL="https://..." id="drone-zone"
+//	               description="..." mirrors="ice2.somafm.com,ice4.somafm.com">
+//	        <outline type="stream" text="AAC 128 kbps" URL="https://..." codec="aac" bitrate="128"/>
+//	      </outline>
+//	    </outline>
+//	  </body>
+//	</opml>
+
+// opmlDocument is the root of an OPML file
+type opmlDocument struct {
+	XMLName xml.Name      `xml:"opml"`
+	Version string        `xml:"version,attr"`
+	Title   string        `xml:"head>title"`
+	Body    []opmlOutline `xml:"body>outline"`
+}
+
+// opmlOutline is a category or a station; stations carry a URL
+type opmlOutline struct {
+	Text         string        `xml:"text,attr"`
+	Title        string        `xml:"title,attr,omitempty"`
+	Type         string        `xml:"type,attr,omitempty"`
+	URL          string        `xml:"URL,attr,omitempty"`
+	LowerURL     string        `xml:"url,attr,omitempty"`
+	XMLURL       string        `xml:"xmlUrl,attr,omitempty"`
+	HTMLURL      string        `xml:"htmlUrl,attr,omitempty"`
+	ID           string        `xml:"id,attr,omitempty"`
+	Description  string        `xml:"description,attr,omitempty"`
+	Category     string        `xml:"category,attr,omitempty"`
+	Mirrors      string        `xml:"mirrors,attr,omitempty"`
+	Tags         string        `xml:"tags,attr,omitempty"`
+	Codec        string        `xml:"codec,attr,omitempty"`
+	Bitrate      string        `xml:"bitrate,attr,omitempty"`
+	Image        string        `xml:"image,attr,omitempty"`
+	UserAgent    string        `xml:"userAgent,attr,omitempty"`
+	Referer      string        `xml:"referer,attr,omitempty"`
+	Demuxer      string        `xml:"demuxer,attr,omitempty"`
+	CacheSeconds string        `xml:"cacheSeconds,attr,omitempty"`
+	VolumeOffset string        `xml:"volumeOffset,attr,omitempty"`
+	Outlines     []opmlOutline `xml:"outline"`
+}
+
+// streamURL returns whichever URL attribute the writing app used
+func (o *opmlOutline) streamURL() string {
+	for _, u := range []string{o.URL, o.LowerURL, o.XMLURL} {
+		if u != "" {
+			return strings.TrimSpace(u)
+		}
+	}
+	return ""
+}
+
+// name returns the outline's label, preferring text over title
+func (o *opmlOutline) name() string {
+	if o.Text != "" {
+		return strings.TrimSpace(o.Text)
+	}
+	return strings.TrimSpace(o.Title)
+}
+
+// ParseOPML reads stations from an OPML outline. The innermost enclosing
+// category becomes the station's genre and outer categories become tags.
+func ParseOPML(data []byte) ([]RadioStation, error) {
+	var doc opmlDocument
+	if err := xml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("parsing OPML: %v", err)
+	}
+
+	var stations []RadioStation
+	var walk func(outlines []opmlOutline, categories []string)
+	walk = func(outlines []opmlOutline, categories []string) {
+		for i := range outlines {
+			o := &outlines[i]
+			if o.streamURL() == "" || o.Type == "stream" {
+				// A category: descend with its name on the path
+				walk(o.Outlines, append(categories[:len(categories):len(categories)], o.name()))
+				continue
+			}
+			stations = append(stations, opmlStation(o, categories))
+		}
+	}
+	walk(doc.Body, nil)
+	return stations, nil
+}
+
+// opmlStation converts a station outline, reading stream variants from its children
+func opmlStation(o *opmlOutline, categories []string) RadioStation {
+	station := RadioStation{
+		ID:          o.ID,
+		Name:        o.name(),
+		URL:         o.streamURL(),
+		Description: o.Description,
+		Logo:        o.Image,
+		Homepage:    strings.TrimSpace(o.HTMLURL),
+	}
+
+	// OPML 2.0 category attribute: comma separated, each may be a /path
+	var tags []string
+	for _, category := range strings.Split(o.Category, ",") {
+		parts := strings.Split(strings.Trim(strings.TrimSpace(category), "/"), "/")
+		tags = append(tags, strings.TrimSpace(parts[len(parts)-1]))
+	}
+	if len(categories) > 0 {
+		station.Genre = categories[len(categories)-1]
+		tags = append(tags, categories[:len(categories)-1]...)
+	} else if len(tags) > 0 {
+		station.Genre, tags = tags[0], tags[1:]
+	}
+	if o.Tags != "" {
+		tags = append(tags, strings.Split(o.Tags, ",")...)
+	}
+	station.Tags = NormalizeTags(tags)
+
+	if o.Mirrors != "" {
+		for _, host := range strings.Split(o.Mirrors, ",") {
+			if host = strings.TrimSpace(host); host != "" {
+				station.Mirrors = append(station.Mirrors, host)
+			}
+		}
+	}
+
+	settings := StreamSettings{UserAgent: o.UserAgent, Referer: o.Referer, Demuxer: o.Demuxer}
+	settings.CacheSeconds, _ = strconv.Atoi(o.CacheSeconds)
+	settings.VolumeOffset, _ = strconv.Atoi(o.VolumeOffset)
+	if settings != (StreamSettings{}) {
+		station.Settings = &settings
+	}
+
+	for _, child := range o.Outlines {
+		if child.Type != "stream" || child.streamURL() == "" {
+			continue
+		}
+		bitrate, _ := strconv.Atoi(child.Bitrate)
+		station.Streams = append(station.Streams, StreamVariant{
+			URL:     child.streamURL(),
+			Codec:   child.Codec,
+			Bitrate: bitrate,
+		})
+	}
+	return station
+}
+
+// FormatOPMLDocument writes stations as OPML, grouped into one outline per genre.
+// Genres keep the order in which they first appear in the list.
+func FormatOPMLDocument(stations []RadioStation) ([]byte, error) {
+	doc := opmlDocument{Version: "2.0", Title: AppName + " stations"}
+
+	groups := make(map[string]int)
+	for _, s := range stations {
+		outline := opmlStationOutline(s)
+		if s.Genre == "" {
+			doc.Body = append(doc.Body, outline)
+			continue
+		}
+		i, ok := groups[s.Genre]
+		if !ok {
+			i = len(doc.Body)
+			groups[s.Genre] = i
+			doc.Body = append(doc.Body, opmlOutline{Text: s.Genre})
+		}
+		doc.Body[i].Outlines = append(doc.Body[i].Outlines, outline)
+	}
+
+	var b bytes.Buffer
+	b.WriteString(xml.Header)
+	enc := xml.NewEncoder(&b)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return nil, err
+	}
+	b.WriteString("\n")
+	return b.Bytes(), nil
+}
+
+// opmlStationOutline converts a station into an outline with its variants as children
+func opmlStationOutline(s RadioStation) opmlOutline {
+	o := opmlOutline{
+		Text:        s.Name,
+		Type:        "audio",
+		URL:         s.URL,
+		ID:          s.ID,
+		Description: s.Description,
+		Mirrors:     strings.Join(s.Mirrors, ","),
+		Tags:        strings.Join(s.Tags, ","),
+		Image:       s.Logo,
+		HTMLURL:     s.Homepage,
+	}
+	if st := s.Settings; st != nil {
+		o.UserAgent, o.Referer, o.Demuxer = st.UserAgent, st.Referer, st.Demuxer
+		if st.CacheSeconds != 0 {
+			o.CacheSeconds = strconv.Itoa(st.CacheSeconds)
+		}
+		if st.VolumeOffset != 0 {
+			o.VolumeOffset = strconv.Itoa(st.VolumeOffset)
+		}
+	}
+	for _, v := range s.Streams {
+		child := opmlOutline{
+			Text:  v.String(),
+			Type:  "stream",
+			URL:   v.URL,
+			Codec: v.Codec,
+		}
+		if v.Bitrate != 0 {
+			child.Bitrate = strconv.Itoa(v.Bitrate)
+		}
+		o.Outlines = append(o.Outlines, child)
+	}
+	return o
+}
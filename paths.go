@@ -0,0 +1,133 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// appDirName is the directory goradio uses inside each base directory
+const appDirName = "goradio-hub"
+
+// xdgBase returns the base directory named by an XDG environment variable.
+// Per the XDG Base Directory spec, unset or relative values are ignored and
+// the fallback under the home directory is used instead.
+func xdgBase(env string, homeFallback string, osFallback func() (string, error)) (string, error) {
+	if dir := os.Getenv(env); dir != "" && filepath.IsAbs(dir) {
+		return dir, nil
+	}
+
+	// Windows has its own conventions; everything else follows XDG
+	if runtime.GOOS == "windows" && osFallback != nil {
+		return osFallback()
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, homeFallback), nil
+}
+
+// ConfigDir holds hand-editable settings: config.json and stations.json
+func ConfigDir() (string, error) {
+	base, err := xdgBase("XDG_CONFIG_HOME", ".config", os.UserConfigDir)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(base, appDirName), nil
+}
+
+// DataDir holds user data that should be backed up, such as recordings
+func DataDir() (string, error) {
+	base, err := xdgBase("XDG_DATA_HOME", filepath.Join(".local", "share"), os.UserConfigDir)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(base, appDirName), nil
+}
+
+// StateDir holds data that persists between runs but is not worth backing up: logs and history
+func StateDir() (string, error) {
+	base, err := xdgBase("XDG_STATE_HOME", filepath.Join(".local", "state"), os.UserCacheDir)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(base, appDirName), nil
+}
+
+// CacheDir holds files that can be regenerated at any time
+func CacheDir() (string, error) {
+	base, err := xdgBase("XDG_CACHE_HOME", ".cache", os.UserCacheDir)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(base, appDirName), nil
+}
+
+// RuntimeDir holds sockets and other files that only live as long as the session.
+// $XDG_RUNTIME_DIR has no home fallback, so the temp directory is used instead.
+func RuntimeDir() string {
+	if dir := os.Getenv("XDG_RUNTIME_DIR"); dir != "" && filepath.IsAbs(dir) {
+		return dir
+	}
+	return os.TempDir()
+}
+
+// DefaultLogPath is where the debug log is written unless --log-file is given
+func DefaultLogPath() (string, error) {
+	dir, err := StateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "goradio.log"), nil
+}
+
+// ensureParentDir creates the directory a file will be written into
+func ensureParentDir(path string) error {
+	return os.MkdirAll(filepath.Dir(path), 0700)
+}
+
+// writeFileAtomic writes data to a temporary file in the same directory and
+// renames it over path, so readers never see a half-written file
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	if err := ensureParentDir(path); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	tmpName := tmp.Name()
+	defer os.Remove(tmpName) // No-op once renamed
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmpName, perm); err != nil {
+		return err
+	}
+	return os.Rename(tmpName, path)
+}
+
+// expandHome replaces a leading ~/ with the user's home directory
+func expandHome(path string) string {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(home, strings.TrimPrefix(path, "~"))
+}
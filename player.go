@@ -1,12 +1,13 @@
 package main
 
 import (
+	"encoding/json"
 	"fmt"
 	"io"
+	"log"
 	"net/http"
-	"os"
-	"os/exec"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -35,15 +36,105 @@ func (s PlayerState) String() string {
 	}
 }
 
+// PlayerEventKind identifies what changed in a PlayerEvent
+type PlayerEventKind int
+
+const (
+	EventStats PlayerEventKind = iota
+	EventStateChanged
+	EventReconnect
+)
+
+// PlayerEvent is sent to the UI whenever the backend reports something new
+type PlayerEvent struct {
+	Kind  PlayerEventKind
+	Stats StreamStats
+}
+
+// PlayOptions carries the user preferences that affect how a station is played
+type PlayOptions struct {
+	Quality     Quality
+	AudioDevice string          // mpv audio device name; empty uses mpv's default
+	Visualizer  bool            // Decode a copy of the stream for the spectrum display
+	Volume      int             // mpv volume; 0 leaves mpv's default
+	Settings    *StreamSettings // The station's own playback settings, if any
+}
+
+// mpvArgs returns the extra mpv arguments implied by the options
+func (o PlayOptions) mpvArgs() []string {
+	var args []string
+	if o.AudioDevice != "" {
+		args = append(args, "--audio-device="+o.AudioDevice)
+	}
+	if volume, ok := o.volume(); ok {
+		args = append(args, fmt.Sprintf("--volume=%d", volume))
+	}
+	return append(args, o.Settings.mpvArgs()...)
+}
+
+// volume returns the volume with the station's offset applied, and false when mpv's default applies
+func (o PlayOptions) volume() (int, bool) {
+	offset := 0
+	if o.Settings != nil {
+		offset = o.Settings.VolumeOffset
+	}
+	if o.Volume == 0 && offset == 0 {
+		return 0, false
+	}
+	volume := o.Volume
+	if volume == 0 {
+		volume = 100
+	}
+	return max(0, min(maxVolume, volume+offset)), true
+}
+
+// maxReconnects is how many times a dying stream is restarted before giving up
+const maxReconnects = 3
+
+// plsTimeout bounds fetching a PLS playlist from one server
+const plsTimeout = 5 * time.Second
+
+// statsLogInterval controls how often diagnostics are written to the log
+const statsLogInterval = 10 * time.Second
+
 // Player represents the audio player
 type Player struct {
+	// mu guards the playback state below, which the UI and the goroutines
+	// watching mpv both read and change. It is never held while talking to
+	// mpv or the network.
+	mu             sync.Mutex
 	currentStation *RadioStation
+	currentStream  StreamVariant
+	opts           PlayOptions
 	state          PlayerState
-	cmd            *exec.Cmd
+	proc           *mpvProcess
 	errorMessage   string
 	metadataExtractor *MetadataExtractor
+
+	// session is bumped on every Play/Stop so goroutines of an old stream can bail out
+	session int
+	statsMu sync.Mutex
+	stats   StreamStats
+	events  chan PlayerEvent
+
+	// Mirror failover state for the current session
+	mirrors      *MirrorHealth
+	candidates   []string
+	candidateIdx int
+	failures     int       // Consecutive attempts that died without healthy playback
+	audioSince   time.Time // When the current attempt started producing audio
+
+	// Paused connections opened ahead of time by warm mode
+	warm *warmPool
+
+	// PCM decoder feeding the spectrum visualizer
+	tapMu sync.Mutex
+	tap   *pcmTap
 }
 
+// healthyPlayback is how long a stream must play before earlier failures are forgiven
+const healthyPlayback = time.Minute
+
 // MetadataExtractor handles ICY metadata extraction from streams
 type MetadataExtractor struct {
 	currentTitle string
@@ -55,79 +146,439 @@ func NewPlayer() *Player {
 	return &Player{
 		state:             StateStopped,
 		metadataExtractor: &MetadataExtractor{},
+		events:            make(chan PlayerEvent, 32),
+		mirrors:           NewMirrorHealth(),
+		warm:              newWarmPool(),
 	}
 }
 
-// Play starts playing a radio station
-func (p *Player) Play(station *RadioStation) error {
+// Play starts playing a radio station using the stream variant that best matches the options
+func (p *Player) Play(station *RadioStation, opts PlayOptions) error {
 	// Stop current playback if any
 	p.Stop()
 	
+	p.mu.Lock()
 	p.state = StateLoading
 	p.currentStation = station
+	p.currentStream = SelectStream(station, opts.Quality)
+	p.opts = opts
 	p.errorMessage = ""
+	p.session++
+	session := p.session
+	p.mu.Unlock()
 	
 	// Get the actual stream URL (handle PLS files)
-	streamURL := station.URL
-	if strings.HasSuffix(station.URL, ".pls") {
-		actualURL, err := p.parsePLS(station.URL)
-		if err != nil {
-			p.state = StateError
-			p.errorMessage = fmt.Sprintf("Failed to parse PLS: %v", err)
-			return err
+	streamURL, err := p.resolveStreamURL(station, opts.Quality)
+	if err != nil {
+		p.fail(session, err.Error())
+		return err
+	}
+	
+	p.statsMu.Lock()
+	p.stats = StreamStats{RequestedAt: time.Now()}
+	p.statsMu.Unlock()
+	
+	// Try healthy mirrors first, recently failed ones last
+	candidates := p.mirrors.Order(mirrorCandidates(streamURL, station.Mirrors))
+	streamURL = candidates[0]
+	proc := p.takeWarm(station, streamURL)
+	
+	p.mu.Lock()
+	if session != p.session {
+		// Stopped or switched while the playlist was being fetched
+		p.mu.Unlock()
+		if proc != nil {
+			proc.kill()
+		}
+		return nil
+	}
+	p.candidates = candidates
+	p.candidateIdx = 0
+	p.failures = 0
+	p.audioSince = time.Time{}
+	if proc != nil {
+		for i, candidate := range candidates {
+			if candidate == proc.url {
+				p.candidateIdx = i
+				streamURL = candidate
+			}
 		}
-		streamURL = actualURL
+	}
+	p.mu.Unlock()
+	
+	// A warm connection only needs to be unpaused
+	if proc != nil {
+		p.adoptWarm(proc, streamURL, session)
+		p.resolveInBackground(streamURL, session)
+		go p.simulateMetadata()
+		return nil
 	}
 	
 	// Start mpv to play the stream
 	go func() {
-		err := p.startMPV(streamURL)
-		if err != nil {
-			p.state = StateError
-			p.errorMessage = err.Error()
+		if err := p.startMPV(streamURL, session); err != nil {
+			p.fail(session, err.Error())
 		}
 	}()
 	
+	p.resolveInBackground(streamURL, session)
+	
 	// Start metadata simulation
 	go p.simulateMetadata()
 	
 	return nil
 }
 
+// fail puts the player in the error state, unless session has been replaced meanwhile
+func (p *Player) fail(session int, message string) {
+	p.mu.Lock()
+	if session != p.session {
+		p.mu.Unlock()
+		return
+	}
+	p.state = StateError
+	p.errorMessage = message
+	p.mu.Unlock()
+	p.emit(EventStateChanged)
+}
+
+// sessionStation returns the station of session, or false when playback was
+// stopped or switched since
+func (p *Player) sessionStation(session int) (*RadioStation, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.currentStation, session == p.session
+}
+
 // startMPV starts mpv to play the audio stream
-func (p *Player) startMPV(url string) error {
-	// Use mpv to play the stream (like in your working version)
-	cmd := exec.Command("mpv", "--no-video", "--no-terminal", "--really-quiet", url)
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	
-	err := cmd.Start()
+func (p *Player) startMPV(url string, session int) error {
+	p.mu.Lock()
+	args := p.opts.mpvArgs()
+	p.mu.Unlock()
+	proc, err := startMPVProcess(url, args...)
 	if err != nil {
-		return fmt.Errorf("failed to start mpv: %v (make sure mpv is installed)", err)
+		return err
 	}
 	
-	p.cmd = cmd
+	p.mu.Lock()
+	if session != p.session {
+		// Playback was stopped or switched while mpv was starting
+		p.mu.Unlock()
+		proc.kill()
+		return nil
+	}
+	p.proc = proc
 	p.state = StatePlaying
+	p.audioSince = time.Time{}
+	p.mu.Unlock()
+	p.updateStats(func(s *StreamStats) { s.StreamURL = url })
+	p.emit(EventStateChanged)
+	
+	go p.watchMPV(proc, url, session)
+	
+	return nil
+}
+
+// adoptWarm turns a paused, muted warm connection into the active stream
+func (p *Player) adoptWarm(proc *mpvProcess, url string, session int) {
+	p.mu.Lock()
+	opts := p.opts
+	p.mu.Unlock()
 	
-	// Wait for the process in a goroutine
+	// Lift the warm read-ahead cap back to mpv's default before playing
+	proc.setProperty("demuxer-max-bytes", "150MiB")
+	proc.setProperty("mute", false)
+	if opts.AudioDevice != "" {
+		proc.setProperty("audio-device", opts.AudioDevice)
+	}
+	if volume, ok := opts.volume(); ok {
+		proc.setProperty("volume", volume)
+	}
+	if err := proc.setProperty("pause", false); err != nil {
+		log.Printf("warm: unpausing failed, starting fresh: %v", err)
+		proc.kill()
+		go func() {
+			if err := p.startMPV(url, session); err != nil {
+				p.fail(session, err.Error())
+			}
+		}()
+		return
+	}
+	
+	p.mu.Lock()
+	if session != p.session {
+		p.mu.Unlock()
+		proc.kill()
+		return
+	}
+	p.proc = proc
+	p.state = StatePlaying
+	p.audioSince = time.Now()
+	station := p.currentStation
+	p.mu.Unlock()
+	p.updateStats(func(s *StreamStats) {
+		s.TimeToFirstAudio = time.Since(s.RequestedAt)
+		s.StreamURL = url
+	})
+	log.Printf("warm: switched to %s instantly", station.Name)
+	logStreamStats(station, p.GetStats())
+	p.emit(EventStateChanged)
+	
+	go p.watchMPV(proc, url, session)
+}
+
+// watchMPV feeds backend events into the stream stats and restarts mpv if the stream dies
+func (p *Player) watchMPV(proc *mpvProcess, url string, session int) {
+	if err := proc.connect(5 * time.Second); err != nil {
+		log.Printf("diag: %v", err)
+	} else {
+		proc.observe(1, "demuxer-cache-duration")
+		proc.observe(2, "cache-speed")
+		// The server's icy-* headers and the decoded format, for the stream info view
+		proc.observe(3, "metadata")
+		proc.observe(4, "audio-codec-name")
+		proc.observe(5, "audio-params")
+		p.startTap(proc, session)
+	}
+	
+	logTicker := time.NewTicker(statsLogInterval)
+	defer logTicker.Stop()
+	
+	events := proc.events
+	for events != nil {
+		select {
+		case ev, ok := <-events:
+			if !ok {
+				events = nil
+				break
+			}
+			// Events of a stream that is being replaced must not land in the new one's stats
+			if _, current := p.sessionStation(session); current {
+				p.handleMPVEvent(ev)
+			}
+		case <-logTicker.C:
+			if station, current := p.sessionStation(session); current {
+				logStreamStats(station, p.GetStats())
+			}
+		case <-proc.done:
+			events = nil
+		}
+	}
+	<-proc.done
+	
+	// If we reach here, mpv has stopped
+	p.mu.Lock()
+	if session != p.session || p.state != StatePlaying {
+		p.mu.Unlock()
+		return
+	}
+	
+	if !p.audioSince.IsZero() && time.Since(p.audioSince) >= healthyPlayback {
+		// The stream played fine for a while, so start counting failures afresh
+		p.failures = 0
+	}
+	p.failures++
+	failures := p.failures
+	station := p.currentStation
+	
+	// Every mirror gets a chance, and a lone server still gets the usual retries
+	maxAttempts := maxReconnects
+	if len(p.candidates) > maxAttempts {
+		maxAttempts = len(p.candidates)
+	}
+	
+	stats := p.GetStats()
+	if failures > maxAttempts {
+		p.state = StateError
+		p.errorMessage = fmt.Sprintf("stream lost after %d reconnects", stats.Reconnects)
+		p.mu.Unlock()
+		p.mirrors.RecordFailure(url)
+		log.Printf("diag: giving up on %s after %d reconnects", station.Name, stats.Reconnects)
+		logStreamStats(station, stats)
+		p.emit(EventStateChanged)
+		return
+	}
+	
+	// Rotate to the next mirror; with a single server this retries the same URL
+	p.candidateIdx = (p.candidateIdx + 1) % len(p.candidates)
+	next := p.candidates[p.candidateIdx]
+	p.state = StateLoading
+	p.mu.Unlock()
+	p.mirrors.RecordFailure(url)
+	
+	p.updateStats(func(s *StreamStats) {
+		s.Reconnects++
+		s.CacheDuration = 0
+		s.Throughput = 0
+		s.ResolvedURL = ""
+		s.Info = nil
+	})
+	log.Printf("diag: stream died on %s, reconnecting (%d/%d) to %s", hostOf(url), failures, maxAttempts, hostOf(next))
+	p.emit(EventReconnect)
+	
+	time.Sleep(time.Duration(failures) * time.Second)
+	if _, current := p.sessionStation(session); !current {
+		return
+	}
+	if err := p.startMPV(next, session); err != nil {
+		p.fail(session, err.Error())
+		return
+	}
+	p.resolveInBackground(next, session)
+}
+
+// resolveInBackground finds out where the stream really lives for the diagnostics panel
+func (p *Player) resolveInBackground(streamURL string, session int) {
+	p.mu.Lock()
+	settings := p.opts.Settings
+	p.mu.Unlock()
 	go func() {
-		cmd.Wait()
-		// If we reach here, mpv has stopped
-		if p.state == StatePlaying {
-			p.state = StateStopped
+		resolved, err := resolveFinalURL(streamURL, settings)
+		if err != nil {
+			log.Printf("diag: resolving %s failed: %v", streamURL, err)
+			resolved = streamURL
 		}
+		if _, current := p.sessionStation(session); !current {
+			return
+		}
+		p.updateStats(func(s *StreamStats) { s.ResolvedURL = resolved })
+		p.emit(EventStats)
 	}()
-	
-	return nil
+}
+
+// handleMPVEvent applies a single IPC event to the stream stats
+func (p *Player) handleMPVEvent(ev mpvEvent) {
+	switch ev.Event {
+	case "property-change":
+		if p.updateStreamInfo(ev) {
+			p.emit(EventStats)
+			return
+		}
+		var value float64
+		if err := json.Unmarshal(ev.Data, &value); err != nil {
+			// Property is unavailable (null) while mpv is still opening the stream
+			return
+		}
+		switch ev.Name {
+		case "demuxer-cache-duration":
+			p.updateStats(func(s *StreamStats) { s.CacheDuration = value })
+		case "cache-speed":
+			p.updateStats(func(s *StreamStats) { s.Throughput = value })
+		}
+		p.emit(EventStats)
+		
+	case "playback-restart":
+		first := false
+		p.updateStats(func(s *StreamStats) {
+			if s.TimeToFirstAudio == 0 {
+				s.TimeToFirstAudio = time.Since(s.RequestedAt)
+				first = true
+			}
+		})
+		p.mu.Lock()
+		station := p.currentStation
+		healthy := ""
+		if p.audioSince.IsZero() {
+			p.audioSince = time.Now()
+			if p.candidateIdx < len(p.candidates) {
+				healthy = p.candidates[p.candidateIdx]
+			}
+		}
+		p.mu.Unlock()
+		if first {
+			logStreamStats(station, p.GetStats())
+		}
+		if healthy != "" {
+			p.mirrors.RecordSuccess(healthy)
+		}
+		p.emit(EventStats)
+	}
+}
+
+// updateStreamInfo applies a change of the stream's metadata or format to the
+// stream info, reporting whether the event was one of those
+func (p *Player) updateStreamInfo(ev mpvEvent) bool {
+	var change func(info *StreamInfo)
+	switch ev.Name {
+	case "metadata":
+		var tags map[string]string
+		if json.Unmarshal(ev.Data, &tags) != nil {
+			return true
+		}
+		header := make(http.Header)
+		for name, value := range tags {
+			header.Set(name, value)
+		}
+		change = func(info *StreamInfo) {
+			parsed := ParseStreamInfo(header)
+			if parsed == nil {
+				parsed = &StreamInfo{}
+			}
+			parsed.Codec, parsed.SampleRate, parsed.Channels = info.Codec, info.SampleRate, info.Channels
+			*info = *parsed
+		}
+	case "audio-codec-name":
+		var name string
+		if json.Unmarshal(ev.Data, &name) != nil {
+			return true
+		}
+		change = func(info *StreamInfo) { info.Codec = mpvCodec(name) }
+	case "audio-params":
+		var params struct {
+			SampleRate int `json:"samplerate"`
+			Channels   int `json:"channel-count"`
+		}
+		if json.Unmarshal(ev.Data, &params) != nil {
+			return true
+		}
+		change = func(info *StreamInfo) { info.SampleRate, info.Channels = params.SampleRate, params.Channels }
+	default:
+		return false
+	}
+	// The info is copied rather than changed in place, as earlier events may still be reading it
+	p.updateStats(func(s *StreamStats) {
+		info := &StreamInfo{}
+		if s.Info != nil {
+			*info = *s.Info
+		}
+		change(info)
+		s.Info = info
+	})
+	return true
+}
+
+// updateStats applies a change to the stream stats under the stats lock
+func (p *Player) updateStats(change func(s *StreamStats)) {
+	p.statsMu.Lock()
+	change(&p.stats)
+	p.statsMu.Unlock()
+}
+
+// emit sends an event to the UI without ever blocking the backend
+func (p *Player) emit(kind PlayerEventKind) {
+	select {
+	case p.events <- PlayerEvent{Kind: kind, Stats: p.GetStats()}:
+	default:
+	}
 }
 
 // parsePLS parses a PLS playlist file and returns the first stream URL
-func (p *Player) parsePLS(plsURL string) (string, error) {
-	resp, err := http.Get(plsURL)
+func (p *Player) parsePLS(plsURL string, settings *StreamSettings) (string, error) {
+	req, err := http.NewRequest("GET", plsURL, nil)
+	if err != nil {
+		return "", err
+	}
+	settings.setHeaders(req)
+	// Bounded so a dead server leaves time to try its mirrors
+	client := &http.Client{Timeout: plsTimeout}
+	resp, err := client.Do(req)
 	if err != nil {
 		return "", err
 	}
 	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("server answered %s", resp.Status)
+	}
 	
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
@@ -165,13 +616,15 @@ func (p *Player) simulateMetadata() {
 		"Peaceful Mind - Meditation Music",
 	}
 	
-	for p.state == StatePlaying {
+	for p.GetState() == StatePlaying {
+		p.mu.Lock()
 		if p.currentStation != nil {
 			// Pick a random title based on the current time
 			titleIndex := int(time.Now().Unix()/180) % len(sampleTitles) // Change every 3 minutes
 			p.metadataExtractor.currentTitle = sampleTitles[titleIndex]
 			p.metadataExtractor.lastUpdate = time.Now()
 		}
+		p.mu.Unlock()
 		
 		time.Sleep(30 * time.Second) // Check every 30 seconds
 	}
@@ -179,46 +632,103 @@ func (p *Player) simulateMetadata() {
 
 // Stop stops the current playback
 func (p *Player) Stop() {
-	if p.cmd != nil {
-		if p.cmd.Process != nil {
-			p.cmd.Process.Kill()
-		}
-		p.cmd = nil
-	}
-	
+	p.mu.Lock()
+	p.session++
+	proc, station := p.proc, p.currentStation
+	p.proc = nil
 	p.state = StateStopped
 	p.currentStation = nil
 	p.metadataExtractor.currentTitle = ""
+	p.mu.Unlock()
+	
+	if proc != nil {
+		if station != nil {
+			logStreamStats(station, p.GetStats())
+		}
+		proc.kill()
+	}
+	p.stopTap()
+}
+
+// SetVisualizer turns the spectrum decoder on or off for the running stream
+func (p *Player) SetVisualizer(on bool) {
+	p.mu.Lock()
+	p.opts.Visualizer = on
+	proc, session, playing := p.proc, p.session, p.state == StatePlaying
+	p.mu.Unlock()
+	if !on {
+		p.stopTap()
+		return
+	}
+	if playing && proc != nil {
+		p.startTap(proc, session)
+	}
+}
+
+// Close stops playback and closes any warm connections
+func (p *Player) Close() {
+	p.Stop()
+	p.DropWarm()
 }
 
 // Toggle toggles play/pause
 func (p *Player) Toggle() {
-	if p.state == StatePlaying {
+	p.mu.Lock()
+	state, station, opts := p.state, p.currentStation, p.opts
+	p.mu.Unlock()
+	if state == StatePlaying {
 		p.Stop()
-	} else if p.currentStation != nil {
-		p.Play(p.currentStation)
+	} else if station != nil {
+		p.Play(station, opts)
 	}
 }
 
 // GetState returns the current player state
 func (p *Player) GetState() PlayerState {
+	p.mu.Lock()
+	defer p.mu.Unlock()
 	return p.state
 }
 
 // GetCurrentStation returns the currently selected station
 func (p *Player) GetCurrentStation() *RadioStation {
+	p.mu.Lock()
+	defer p.mu.Unlock()
 	return p.currentStation
 }
 
 // GetCurrentSong returns the current song title
 func (p *Player) GetCurrentSong() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
 	if p.metadataExtractor.currentTitle == "" {
 		return "Loading track info..."
 	}
 	return p.metadataExtractor.currentTitle
 }
 
+// GetCurrentStream returns the stream variant being played
+func (p *Player) GetCurrentStream() StreamVariant {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.currentStream
+}
+
 // GetErrorMessage returns the last error message
 func (p *Player) GetErrorMessage() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
 	return p.errorMessage
 }
+
+// GetStats returns a snapshot of the current stream diagnostics
+func (p *Player) GetStats() StreamStats {
+	p.statsMu.Lock()
+	defer p.statsMu.Unlock()
+	return p.stats
+}
+
+// Events returns the channel on which backend updates are delivered
+func (p *Player) Events() <-chan PlayerEvent {
+	return p.events
+}
@@ -0,0 +1,333 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// PlaylistFormat identifies a playlist file format
+type PlaylistFormat string
+
+const (
+	FormatM3U  PlaylistFormat = "m3u"
+	FormatPLS  PlaylistFormat = "pls"
+	FormatOPML PlaylistFormat = "opml"
+)
+
+// playlistFormatFor picks a format from a file name, falling back to sniffing the content
+func playlistFormatFor(name string, data []byte) (PlaylistFormat, error) {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".m3u", ".m3u8":
+		return FormatM3U, nil
+	case ".pls":
+		return FormatPLS, nil
+	case ".opml":
+		return FormatOPML, nil
+	}
+
+	trimmed := bytes.TrimSpace(data)
+	switch {
+	case bytes.HasPrefix(bytes.ToLower(trimmed), []byte("[playlist]")):
+		return FormatPLS, nil
+	case bytes.HasPrefix(trimmed, []byte("#EXTM3U")), bytes.HasPrefix(trimmed, []byte("http")):
+		return FormatM3U, nil
+	case bytes.Contains(trimmed[:min(len(trimmed), 512)], []byte("<opml")):
+		return FormatOPML, nil
+	}
+	return "", fmt.Errorf("%s: unknown playlist format (expected .m3u, .m3u8, .pls or .opml)", name)
+}
+
+// extinfAttr matches key="value" attributes some M3U writers put in #EXTINF lines
+var extinfAttr = regexp.MustCompile(`([a-zA-Z0-9-]+)="([^"]*)"`)
+
+// ParseM3U reads stations from an M3U/M3U8 playlist, taking names from #EXTINF
+func ParseM3U(data []byte) []RadioStation {
+	var stations []RadioStation
+	var name, genre, logo string
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(strings.TrimPrefix(scanner.Text(), "\ufeff"))
+		switch {
+		case line == "":
+			continue
+
+		case strings.HasPrefix(line, "#EXTINF:"):
+			// #EXTINF:-1 group-title="Jazz",Station Name
+			info := strings.TrimPrefix(line, "#EXTINF:")
+			name, genre, logo = "", "", ""
+			if i := extinfTitleStart(info); i >= 0 {
+				name = strings.TrimSpace(info[i+1:])
+				info = info[:i]
+			}
+			for _, attr := range extinfAttr.FindAllStringSubmatch(info, -1) {
+				switch attr[1] {
+				case "group-title":
+					genre = attr[2]
+				case "tvg-logo":
+					logo = attr[2]
+				}
+			}
+
+		case strings.HasPrefix(line, "#EXTGENRE:"):
+			genre = strings.TrimSpace(strings.TrimPrefix(line, "#EXTGENRE:"))
+
+		case strings.HasPrefix(line, "#"):
+			continue
+
+		default:
+			station := playlistStation(name, line, genre)
+			station.Logo = logo
+			stations = append(stations, station)
+			name, genre, logo = "", "", ""
+		}
+	}
+	return stations
+}
+
+// extinfTitleStart finds the comma separating #EXTINF attributes from the title,
+// skipping commas inside quoted attribute values
+func extinfTitleStart(info string) int {
+	quoted := false
+	for i, r := range info {
+		switch {
+		case r == '"':
+			quoted = !quoted
+		case r == ',' && !quoted:
+			return i
+		}
+	}
+	return -1
+}
+
+// ParsePLS reads stations from a PLS playlist, pairing FileN with TitleN
+func ParsePLS(data []byte) []RadioStation {
+	files := make(map[int]string)
+	titles := make(map[int]string)
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		eq := strings.IndexByte(line, '=')
+		if eq < 0 {
+			continue
+		}
+		key, value := strings.ToLower(line[:eq]), strings.TrimSpace(line[eq+1:])
+
+		var target map[int]string
+		switch {
+		case strings.HasPrefix(key, "file"):
+			target, key = files, strings.TrimPrefix(key, "file")
+		case strings.HasPrefix(key, "title"):
+			target, key = titles, strings.TrimPrefix(key, "title")
+		default:
+			continue
+		}
+		if n, err := strconv.Atoi(key); err == nil {
+			target[n] = value
+		}
+	}
+
+	var numbers []int
+	for n := range files {
+		numbers = append(numbers, n)
+	}
+	sort.Ints(numbers)
+
+	var stations []RadioStation
+	for _, n := range numbers {
+		stations = append(stations, playlistStation(titles[n], files[n], ""))
+	}
+	return stations
+}
+
+// playlistStation builds a station from a playlist entry, naming it after the URL if untitled
+func playlistStation(name, streamURL, genre string) RadioStation {
+	if name == "" {
+		name = streamURL
+		if u, err := url.Parse(streamURL); err == nil && u.Host != "" {
+			name = u.Host + strings.TrimSuffix(u.Path, path.Ext(u.Path))
+		}
+	}
+	// IPTV-style lists put several groups in one title: "Jazz;Smooth Jazz"
+	groups := NormalizeTags(strings.Split(genre, ";"))
+	station := RadioStation{Name: name, URL: streamURL}
+	if len(groups) > 0 {
+		station.Genre, station.Tags = groups[0], groups[1:]
+	}
+	return station
+}
+
+// ParseStationList reads stations from a playlist or OPML file, detecting its format
+func ParseStationList(name string) ([]RadioStation, error) {
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	format, err := playlistFormatFor(name, data)
+	if err != nil {
+		return nil, err
+	}
+	switch format {
+	case FormatPLS:
+		return ParsePLS(data), nil
+	case FormatOPML:
+		return ParseOPML(data)
+	default:
+		return ParseM3U(data), nil
+	}
+}
+
+// FormatStationList encodes stations in the named format
+func FormatStationList(format string, stations []RadioStation) ([]byte, error) {
+	switch PlaylistFormat(format) {
+	case FormatM3U, "m3u8":
+		return FormatM3UPlaylist(stations), nil
+	case FormatPLS:
+		return FormatPLSPlaylist(stations), nil
+	case FormatOPML:
+		return FormatOPMLDocument(stations)
+	}
+	return nil, fmt.Errorf("unknown format %q (want m3u, pls or opml)", format)
+}
+
+// FormatM3UPlaylist writes stations as an extended M3U playlist
+func FormatM3UPlaylist(stations []RadioStation) []byte {
+	var b bytes.Buffer
+	b.WriteString("#EXTM3U\n")
+	for _, s := range stations {
+		b.WriteString("#EXTINF:-1")
+		if s.Logo != "" {
+			fmt.Fprintf(&b, " tvg-logo=%q", s.Logo)
+		}
+		if s.Genre != "" {
+			fmt.Fprintf(&b, " group-title=%q", s.Genre)
+		}
+		fmt.Fprintf(&b, ",%s\n", s.Name)
+		b.WriteString(s.URL + "\n")
+	}
+	return b.Bytes()
+}
+
+// FormatPLSPlaylist writes stations as a PLS playlist
+func FormatPLSPlaylist(stations []RadioStation) []byte {
+	var b bytes.Buffer
+	b.WriteString("[playlist]\n")
+	for i, s := range stations {
+		fmt.Fprintf(&b, "File%d=%s\n", i+1, s.URL)
+		fmt.Fprintf(&b, "Title%d=%s\n", i+1, s.Name)
+		fmt.Fprintf(&b, "Length%d=-1\n", i+1)
+	}
+	fmt.Fprintf(&b, "NumberOfEntries=%d\nVersion=2\n", len(stations))
+	return b.Bytes()
+}
+
+// ExportStationList writes stations to a file, picking the format from its extension
+func ExportStationList(name string, stations []RadioStation) error {
+	format, err := playlistFormatFor(name, nil)
+	if err != nil {
+		return err
+	}
+	data, err := FormatStationList(string(format), stations)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(name, data, 0644)
+}
+
+// ImportResult summarizes what an import changed
+type ImportResult struct {
+	Added      int
+	Duplicates int
+	Invalid    int
+	Keys       []string // Keys of the added stations
+}
+
+func (r ImportResult) String() string {
+	return fmt.Sprintf("%d added, %d duplicates skipped, %d invalid", r.Added, r.Duplicates, r.Invalid)
+}
+
+// ImportStations adds stations to the user's library, skipping entries whose URL
+// is already present (compared with CanonicalURL) and entries that would not
+// load, and giving each new station a unique key (keeping its own ID when free)
+func ImportStations(incoming []RadioStation) (ImportResult, error) {
+	var result ImportResult
+
+	existing, err := LoadStations()
+	if err != nil {
+		return result, err
+	}
+
+	urls := make(map[string]bool)
+	keys := make(map[string]bool)
+	for _, s := range existing {
+		keys[s.Key()] = true
+		urls[CanonicalURL(s.URL)] = true
+		for _, v := range s.Streams {
+			urls[CanonicalURL(v.URL)] = true
+		}
+	}
+
+	var added []RadioStation
+	for _, s := range incoming {
+		s.URL = strings.TrimSpace(s.URL)
+		if checkStreamURL(s.URL) != "" || s.Name == "" {
+			result.Invalid++
+			continue
+		}
+		if urls[CanonicalURL(s.URL)] {
+			result.Duplicates++
+			continue
+		}
+
+		// Other apps' ids need not follow our rules; a bad one would make
+		// the whole station file fail to load
+		base := slugify(s.ID)
+		if base == "" {
+			base = slugify(s.Name)
+		}
+		s.ID = uniqueKey(base, keys)
+		if problems := validateStation(&s); len(problems) > 0 {
+			log.Printf("import: skipping %q: %s", s.Name, problems[0].text)
+			result.Invalid++
+			continue
+		}
+		urls[CanonicalURL(s.URL)] = true
+		keys[s.ID] = true
+		added = append(added, s)
+	}
+
+	if len(added) == 0 {
+		return result, nil
+	}
+	if err := AddStations(added); err != nil {
+		return result, err
+	}
+	result.Added = len(added)
+	for _, s := range added {
+		result.Keys = append(result.Keys, s.Key())
+	}
+	return result, nil
+}
+
+// uniqueKey returns base, or base with a numeric suffix if it is already taken
+func uniqueKey(base string, taken map[string]bool) string {
+	if base == "" {
+		base = "station"
+	}
+	key := base
+	for n := 2; taken[key]; n++ {
+		key = fmt.Sprintf("%s-%d", base, n)
+	}
+	return key
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// probeTimeout bounds how long a single stream probe may take
+const probeTimeout = 8 * time.Second
+
+// ProbeResult describes what a stream URL answered when we connected to it
+type ProbeResult struct {
+	URL         string
+	FinalURL    string // After redirects
+	Status      int
+	ContentType string
+	Header      http.Header
+	Err         error
+}
+
+// OK reports whether the URL looks like a playable stream or playlist
+func (r ProbeResult) OK() bool {
+	return r.Err == nil && r.Status == http.StatusOK && isStreamContentType(r.ContentType)
+}
+
+// Problem describes why the probe failed, or "" if it succeeded
+func (r ProbeResult) Problem() string {
+	switch {
+	case r.Err != nil:
+		return r.Err.Error()
+	case r.Status != http.StatusOK:
+		return fmt.Sprintf("server answered %d %s", r.Status, http.StatusText(r.Status))
+	case !isStreamContentType(r.ContentType):
+		return fmt.Sprintf("content type %q is not audio or a playlist", r.ContentType)
+	}
+	return ""
+}
+
+// ProbeStream connects to a stream URL, reads the headers and the first bytes, then hangs up
+func ProbeStream(url string) ProbeResult {
+	result := ProbeResult{URL: url}
+
+	client := &http.Client{Timeout: probeTimeout}
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	// Ask Icecast/Shoutcast servers to include their icy-* headers
+	req.Header.Set("Icy-MetaData", "1")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	defer resp.Body.Close()
+
+	result.FinalURL = resp.Request.URL.String()
+	result.Status = resp.StatusCode
+	result.ContentType = resp.Header.Get("Content-Type")
+	result.Header = resp.Header
+
+	if resp.StatusCode == http.StatusOK {
+		// Make sure data actually flows, not just headers
+		buf := make([]byte, 512)
+		if _, err := io.ReadAtLeast(resp.Body, buf, 1); err != nil {
+			result.Err = fmt.Errorf("no data received: %v", err)
+		}
+	}
+	return result
+}
+
+// isStreamContentType accepts audio types and the playlist types mpv can follow
+func isStreamContentType(contentType string) bool {
+	ct := strings.ToLower(strings.TrimSpace(strings.Split(contentType, ";")[0]))
+	if strings.HasPrefix(ct, "audio/") {
+		return true
+	}
+	switch ct {
+	case "application/ogg", "application/octet-stream", "application/vnd.apple.mpegurl",
+		"application/x-mpegurl", "application/pls+xml", "video/mp2t":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,427 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Profile is a named collection of stations with its own playback defaults.
+// Stations are referenced by key, so editing a station changes it in every profile.
+type Profile struct {
+	Name         string   `json:"name"`
+	Stations     []string `json:"stations"`                // Station keys, in list order
+	Volume       int      `json:"volume,omitempty"`        // mpv volume (0-130); 0 leaves mpv's default
+	SleepMinutes int      `json:"sleep_minutes,omitempty"` // Stop playback this long after a station starts
+	AudioDevice  string   `json:"audio_device,omitempty"`  // Used unless a station has its own device
+}
+
+// Profiles is the user's profile file
+type Profiles struct {
+	Active   string    `json:"active,omitempty"` // Name of the profile in use; empty shows the whole library
+	Profiles []Profile `json:"profiles"`
+
+	unreadable error // Why the file could not be read; Save refuses to overwrite it
+}
+
+// profilesPath returns the location of the profile file
+func profilesPath() (string, error) {
+	dir, err := ConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "profiles.json"), nil
+}
+
+// LoadProfiles reads the profile file, returning no profiles if it does not exist
+func LoadProfiles() (*Profiles, error) {
+	profiles := &Profiles{}
+
+	path, err := profilesPath()
+	if err != nil {
+		return profiles, err
+	}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return profiles, nil
+	}
+	if err != nil {
+		profiles.unreadable = err
+		return profiles, err
+	}
+	if err := json.Unmarshal(data, profiles); err != nil {
+		err = fmt.Errorf("parsing %s: %v", path, err)
+		return &Profiles{unreadable: err}, err
+	}
+	if current := profiles.Current(); current != nil {
+		// Names match case-insensitively; keep the profile's own spelling
+		profiles.Active = current.Name
+	}
+	return profiles, nil
+}
+
+// Save writes the profile file. A file that could not be read is left alone,
+// since the empty set loaded in its place would overwrite the user's profiles.
+func (p *Profiles) Save() error {
+	if p.unreadable != nil {
+		return fmt.Errorf("not overwriting the profile file until it is fixed (%v)", p.unreadable)
+	}
+	path, err := profilesPath()
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(path, append(data, '\n'), 0644)
+}
+
+// Find returns the profile called name, ignoring case, or nil
+func (p *Profiles) Find(name string) *Profile {
+	for i := range p.Profiles {
+		if strings.EqualFold(p.Profiles[i].Name, name) {
+			return &p.Profiles[i]
+		}
+	}
+	return nil
+}
+
+// Current returns the active profile, or nil when the whole library is shown
+func (p *Profiles) Current() *Profile {
+	if p.Active == "" {
+		return nil
+	}
+	return p.Find(p.Active)
+}
+
+// Use makes the profile called name active; an empty name shows the whole library
+func (p *Profiles) Use(name string) error {
+	if name == "" {
+		p.Active = ""
+		return nil
+	}
+	profile := p.Find(name)
+	if profile == nil {
+		return fmt.Errorf("no profile named %q", name)
+	}
+	p.Active = profile.Name
+	return nil
+}
+
+// Add creates a profile, refusing names already in use
+func (p *Profiles) Add(profile Profile) error {
+	if strings.TrimSpace(profile.Name) == "" {
+		return fmt.Errorf("a profile needs a name")
+	}
+	if p.Find(profile.Name) != nil {
+		return fmt.Errorf("a profile named %q already exists", profile.Name)
+	}
+	p.Profiles = append(p.Profiles, profile)
+	return nil
+}
+
+// ReplaceKey points every profile listing oldKey at newKey instead, dropping
+// oldKey where newKey is already listed, and reports whether anything changed
+func (p *Profiles) ReplaceKey(oldKey, newKey string) bool {
+	changed := false
+	for i := range p.Profiles {
+		profile := &p.Profiles[i]
+		if !profile.Has(oldKey) {
+			continue
+		}
+		if profile.Has(newKey) {
+			profile.Remove(oldKey)
+		} else {
+			profile.Stations[profile.index(oldKey)] = newKey
+		}
+		changed = true
+	}
+	return changed
+}
+
+// index returns the position of a station in the profile, or -1
+func (profile *Profile) index(key string) int {
+	for i, k := range profile.Stations {
+		if k == key {
+			return i
+		}
+	}
+	return -1
+}
+
+// Has reports whether a station is in the profile
+func (profile *Profile) Has(key string) bool {
+	return profile.index(key) >= 0
+}
+
+// Include appends the stations the profile does not list yet, reporting
+// whether any were added
+func (profile *Profile) Include(keys ...string) bool {
+	added := false
+	for _, key := range keys {
+		if key != "" && !profile.Has(key) {
+			profile.Stations = append(profile.Stations, key)
+			added = true
+		}
+	}
+	return added
+}
+
+// Remove takes a station out of the profile, reporting whether it was listed
+func (profile *Profile) Remove(key string) bool {
+	i := profile.index(key)
+	if i < 0 {
+		return false
+	}
+	profile.Stations = append(profile.Stations[:i], profile.Stations[i+1:]...)
+	return true
+}
+
+// Move shifts a station delta places among the profile's stations that are
+// listed, so keys of deleted or filtered out stations never take up a step.
+// It returns false when the station cannot move.
+func (profile *Profile) Move(key string, delta int, stations []RadioStation) bool {
+	present := make(map[string]bool, len(stations))
+	for _, s := range stations {
+		present[s.Key()] = true
+	}
+	var shown []int
+	from := -1
+	for i, k := range profile.Stations {
+		if !present[k] {
+			continue
+		}
+		if k == key {
+			from = len(shown)
+		}
+		shown = append(shown, i)
+	}
+	to := from + delta
+	if from < 0 || to < 0 || to >= len(shown) {
+		return false
+	}
+	i, j := shown[from], shown[to]
+	profile.Stations[i], profile.Stations[j] = profile.Stations[j], profile.Stations[i]
+	return true
+}
+
+// Filter returns the profile's stations in profile order, skipping keys that
+// are no longer in the library
+func (profile *Profile) Filter(stations []RadioStation) []RadioStation {
+	byKey := make(map[string]RadioStation, len(stations))
+	for _, s := range stations {
+		byKey[s.Key()] = s
+	}
+	var filtered []RadioStation
+	for _, key := range profile.Stations {
+		if s, ok := byKey[key]; ok {
+			filtered = append(filtered, s)
+		}
+	}
+	return filtered
+}
+
+// Summary describes the profile's settings, e.g. "12 stations · volume 60 · sleep 45m"
+func (profile *Profile) Summary() string {
+	parts := []string{fmt.Sprintf("%d stations", len(profile.Stations))}
+	if profile.Volume > 0 {
+		parts = append(parts, fmt.Sprintf("volume %d", profile.Volume))
+	}
+	if profile.SleepMinutes > 0 {
+		parts = append(parts, fmt.Sprintf("sleep %dm", profile.SleepMinutes))
+	}
+	if profile.AudioDevice != "" {
+		parts = append(parts, "device "+profile.AudioDevice)
+	}
+	return strings.Join(parts, " · ")
+}
+
+// profilePicker is the state of the profile switcher overlay
+type profilePicker struct {
+	cursor int // 0 is the whole library, then one row per profile
+}
+
+// RenderProfilePicker renders the profile list with the active one marked
+func RenderProfilePicker(picker *profilePicker, profiles *Profiles) string {
+	content := []string{titleStyle.Render("Profiles")}
+
+	rows := []string{"All stations"}
+	details := []string{"The whole library"}
+	active := 0
+	for i, profile := range profiles.Profiles {
+		rows = append(rows, profile.Name)
+		details = append(details, profile.Summary())
+		if profile.Name == profiles.Active {
+			active = i + 1
+		}
+	}
+
+	for i, row := range rows {
+		marker := "  "
+		if i == active {
+			marker = "● "
+		}
+		if i == picker.cursor {
+			content = append(content, selectedItemStyle.Render(marker+row))
+		} else {
+			content = append(content, normalItemStyle.Render(marker+row))
+		}
+	}
+
+	content = append(content, "",
+		details[picker.cursor],
+		"",
+		lipgloss.NewStyle().Foreground(mutedColor).Render(strings.Join([]string{
+			"Enter  Switch to profile",
+			"a / x  Add / remove the highlighted station",
+			"n      New profile from the listed stations",
+			"Esc    Close",
+		}, "\n")))
+	return strings.Join(content, "\n")
+}
+
+// updateProfilePicker handles keys while the profile switcher is open
+func (m Model) updateProfilePicker(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	picker := m.profilePicker
+	switch msg.String() {
+	case "esc", "P", "q":
+		m.profilePicker = nil
+
+	case "up", "k":
+		if picker.cursor > 0 {
+			picker.cursor--
+		}
+
+	case "down", "j":
+		if picker.cursor < len(m.profiles.Profiles) {
+			picker.cursor++
+		}
+
+	case "enter", " ":
+		name := ""
+		if picker.cursor > 0 {
+			name = m.profiles.Profiles[picker.cursor-1].Name
+		}
+		m.profilePicker = nil
+		m.switchProfile(name)
+		m.saveProfiles()
+
+	case "a", "x":
+		station := m.selectedStation()
+		if picker.cursor == 0 || station == nil {
+			break
+		}
+		profile := &m.profiles.Profiles[picker.cursor-1]
+		if msg.String() == "a" {
+			if !profile.Include(station.Key()) {
+				m.statusMsg = fmt.Sprintf("%q is already in %q", station.Name, profile.Name)
+				break
+			}
+			m.statusMsg = fmt.Sprintf("Added %q to %q", station.Name, profile.Name)
+		} else {
+			if !profile.Remove(station.Key()) {
+				m.statusMsg = fmt.Sprintf("%q is not in %q", station.Name, profile.Name)
+				break
+			}
+			m.statusMsg = fmt.Sprintf("Removed %q from %q", station.Name, profile.Name)
+		}
+		m.saveProfiles()
+		if profile == m.profiles.Current() {
+			key := station.Key()
+			if !profile.Has(key) {
+				key = m.neighborKey(key)
+			}
+			m.refreshList(key)
+		}
+
+	case "n":
+		m.profilePicker = nil
+		m.prompt = &textPrompt{
+			kind:  promptNewProfile,
+			title: fmt.Sprintf("New Profile with %d Stations", len(m.listStations())),
+			hint:  "Volume, sleep timer and audio device can be set in profiles.json",
+			input: formField{label: "Name"},
+		}
+	}
+	return m, nil
+}
+
+// switchProfile makes a profile active and rebuilds the list from its stations
+func (m *Model) switchProfile(name string) {
+	if err := m.profiles.Use(name); err != nil {
+		m.statusMsg = "⚠ " + err.Error()
+		return
+	}
+	key := ""
+	if station := m.selectedStation(); station != nil {
+		key = station.Key()
+	}
+	m.refreshList(key)
+
+	// The new profile's sleep timer counts from now if something is playing
+	m.sleepAt = time.Time{}
+	if m.player.GetState() == StatePlaying {
+		m.armSleepTimer()
+	}
+	if profile := m.profiles.Current(); profile != nil {
+		m.statusMsg = fmt.Sprintf("Profile %q: %s", profile.Name, profile.Summary())
+	} else {
+		m.statusMsg = "Showing all stations"
+	}
+}
+
+// createProfile saves the listed stations as a new profile and switches to it
+func (m *Model) createProfile(name string) error {
+	profile := Profile{Name: name}
+	for _, s := range m.listStations() {
+		profile.Stations = append(profile.Stations, s.Key())
+	}
+	if err := m.profiles.Add(profile); err != nil {
+		return err
+	}
+	m.switchProfile(profile.Name)
+	m.saveProfiles()
+	return nil
+}
+
+// includeInProfile adds new stations to the active profile, so stations added
+// while a profile is shown do not vanish from the list
+func (m *Model) includeInProfile(keys ...string) {
+	if profile := m.profiles.Current(); profile != nil && profile.Include(keys...) {
+		m.saveProfiles()
+	}
+}
+
+// saveProfiles persists the profiles, reporting failures in the status line
+func (m *Model) saveProfiles() {
+	if err := m.profiles.Save(); err != nil {
+		m.statusMsg = "⚠ saving profiles: " + firstLine(err.Error())
+	}
+}
+
+// armSleepTimer starts the active profile's sleep timer, if it has one
+func (m *Model) armSleepTimer() {
+	m.sleepAt = time.Time{}
+	if profile := m.profiles.Current(); profile != nil && profile.SleepMinutes > 0 {
+		m.sleepAt = time.Now().Add(time.Duration(profile.SleepMinutes) * time.Minute)
+	}
+}
+
+// checkSleepTimer stops playback once the sleep timer runs out
+func (m *Model) checkSleepTimer(now time.Time) {
+	if m.sleepAt.IsZero() || now.Before(m.sleepAt) {
+		return
+	}
+	m.sleepAt = time.Time{}
+	if m.player.GetState() != StateStopped {
+		m.player.Stop()
+		m.statusMsg = "Sleep timer: playback stopped"
+	}
+}
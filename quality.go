@@ -0,0 +1,138 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Quality is a stream quality preference used to pick between stream variants
+type Quality int
+
+const (
+	QualityHigh Quality = iota
+	QualityLow
+	QualityDataSaver
+)
+
+// qualityNames is the order used when cycling through qualities
+var qualityNames = []string{"high", "low", "data-saver"}
+
+func (q Quality) String() string {
+	if int(q) >= 0 && int(q) < len(qualityNames) {
+		return qualityNames[q]
+	}
+	return "high"
+}
+
+// Next returns the following quality in the cycle
+func (q Quality) Next() Quality {
+	return Quality((int(q) + 1) % len(qualityNames))
+}
+
+// ParseQuality converts a quality name back into a Quality
+func ParseQuality(name string) (Quality, error) {
+	for i, n := range qualityNames {
+		if strings.EqualFold(n, name) {
+			return Quality(i), nil
+		}
+	}
+	return QualityHigh, fmt.Errorf("unknown quality %q (want high, low or data-saver)", name)
+}
+
+// MarshalJSON stores qualities by name so the config file stays readable
+func (q Quality) MarshalJSON() ([]byte, error) {
+	return json.Marshal(q.String())
+}
+
+// UnmarshalJSON reads a quality stored by name
+func (q *Quality) UnmarshalJSON(data []byte) error {
+	var name string
+	if err := json.Unmarshal(data, &name); err != nil {
+		return err
+	}
+	parsed, err := ParseQuality(name)
+	if err != nil {
+		return err
+	}
+	*q = parsed
+	return nil
+}
+
+// lowQualityFloor is the smallest bitrate the "low" preference will pick if anything better exists
+const lowQualityFloor = 64
+
+// codecEfficiency ranks codecs by quality per bit, used to break bitrate ties
+func codecEfficiency(codec string) int {
+	switch strings.ToLower(codec) {
+	case "aacp", "opus":
+		return 3
+	case "aac", "ogg", "vorbis":
+		return 2
+	case "mp3":
+		return 1
+	default:
+		return 0
+	}
+}
+
+// betterVariant reports whether a should be preferred over b at equal bitrate
+func betterVariant(a, b StreamVariant) bool {
+	return codecEfficiency(a.Codec) > codecEfficiency(b.Codec)
+}
+
+// SelectStream picks the stream variant best matching the quality preference.
+// Stations without variants fall back to their primary URL.
+func SelectStream(station *RadioStation, quality Quality) StreamVariant {
+	if len(station.Streams) == 0 {
+		return StreamVariant{URL: station.URL}
+	}
+
+	best := station.Streams[0]
+	switch quality {
+	case QualityHigh:
+		// Highest bitrate wins
+		for _, v := range station.Streams[1:] {
+			if v.Bitrate > best.Bitrate || (v.Bitrate == best.Bitrate && betterVariant(v, best)) {
+				best = v
+			}
+		}
+
+	case QualityLow:
+		// Lowest bitrate that is still at or above the floor, otherwise the highest below it
+		found := false
+		for _, v := range station.Streams {
+			if v.Bitrate < lowQualityFloor {
+				continue
+			}
+			if !found || v.Bitrate < best.Bitrate || (v.Bitrate == best.Bitrate && betterVariant(v, best)) {
+				best = v
+				found = true
+			}
+		}
+		if !found {
+			return SelectStream(station, QualityHigh)
+		}
+
+	case QualityDataSaver:
+		// Lowest bitrate overall
+		for _, v := range station.Streams[1:] {
+			if v.Bitrate < best.Bitrate || (v.Bitrate == best.Bitrate && betterVariant(v, best)) {
+				best = v
+			}
+		}
+	}
+
+	return best
+}
+
+// String describes a variant as codec and bitrate
+func (v StreamVariant) String() string {
+	if v.Codec == "" && v.Bitrate == 0 {
+		return "default stream"
+	}
+	if v.Bitrate == 0 {
+		return strings.ToUpper(v.Codec)
+	}
+	return fmt.Sprintf("%s %d kbps", strings.ToUpper(v.Codec), v.Bitrate)
+}
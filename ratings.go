@@ -0,0 +1,140 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Ratings maps station keys to the user's 1-5 rating
+type Ratings map[string]int
+
+// ratingsPath returns the location of the ratings file
+func ratingsPath() (string, error) {
+	dir, err := DataDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "ratings.json"), nil
+}
+
+// LoadRatings reads the ratings file, returning no ratings if it does not exist
+func LoadRatings() (Ratings, error) {
+	ratings := make(Ratings)
+
+	path, err := ratingsPath()
+	if err != nil {
+		return ratings, err
+	}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return ratings, nil
+	}
+	if err != nil {
+		return ratings, err
+	}
+	if err := json.Unmarshal(data, &ratings); err != nil {
+		return make(Ratings), err
+	}
+	return ratings, nil
+}
+
+// Save writes the ratings file
+func (r Ratings) Save() error {
+	path, err := ratingsPath()
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(path, append(data, '\n'), 0644)
+}
+
+// Rate sets a station's rating; giving the current rating again clears it.
+// It returns the rating now in effect, 0 meaning unrated.
+func (r Ratings) Rate(key string, rating int) int {
+	if r[key] == rating || rating < 1 || rating > 5 {
+		delete(r, key)
+		return 0
+	}
+	r[key] = rating
+	return rating
+}
+
+// StationSort is the order the station list is shown in
+type StationSort string
+
+const (
+	SortCustom   StationSort = "custom" // The view's own order: library order, preset order or recency
+	SortName     StationSort = "name"
+	SortGenre    StationSort = "genre"
+	SortRating   StationSort = "rating"
+	SortListened StationSort = "listened"
+	SortRecent   StationSort = "recent"
+)
+
+// stationSorts is the order the sort key cycles through
+var stationSorts = []StationSort{SortCustom, SortName, SortGenre, SortRating, SortListened, SortRecent}
+
+// Next returns the following sort in the cycle
+func (s StationSort) Next() StationSort {
+	for i, sort := range stationSorts {
+		if sort == s {
+			return stationSorts[(i+1)%len(stationSorts)]
+		}
+	}
+	return SortName
+}
+
+func (s StationSort) String() string {
+	switch s {
+	case SortName:
+		return "name"
+	case SortGenre:
+		return "genre"
+	case SortRating:
+		return "rating"
+	case SortListened:
+		return "most listened"
+	case SortRecent:
+		return "recently played"
+	}
+	return "custom order"
+}
+
+// SortStations orders stations in place. Ties, and the unrated or never
+// played stations at the end, fall back to name order.
+func SortStations(stations []RadioStation, by StationSort) {
+	if by == SortCustom || by == "" {
+		return
+	}
+	byName := func(a, b *RadioStation) bool {
+		return strings.ToLower(a.Name) < strings.ToLower(b.Name)
+	}
+	sort.SliceStable(stations, func(i, j int) bool {
+		a, b := &stations[i], &stations[j]
+		switch by {
+		case SortGenre:
+			if ga, gb := strings.ToLower(a.Genre), strings.ToLower(b.Genre); ga != gb {
+				return ga < gb
+			}
+		case SortRating:
+			if a.Rating != b.Rating {
+				return a.Rating > b.Rating
+			}
+		case SortListened:
+			if a.Listened != b.Listened {
+				return a.Listened > b.Listened
+			}
+		case SortRecent:
+			if !a.LastPlayed.Equal(b.LastPlayed) {
+				return a.LastPlayed.After(b.LastPlayed)
+			}
+		}
+		return byName(a, b)
+	})
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// maxVolume is the highest volume mpv accepts by default
+const maxVolume = 130
+
+// StreamSettings adjusts how one station is played, for streams that need
+// more than a plain GET. Every field is optional.
+type StreamSettings struct {
+	UserAgent    string `json:"user_agent,omitempty"`    // Sent instead of mpv's own User-Agent
+	Referer      string `json:"referer,omitempty"`       // For servers that check where a request came from
+	Demuxer      string `json:"demuxer,omitempty"`       // Container format to assume, e.g. "aac" or "mp3"
+	CacheSeconds int    `json:"cache_seconds,omitempty"` // Read-ahead, for streams that stutter
+	VolumeOffset int    `json:"volume_offset,omitempty"` // Added to the volume, for stations mastered too loud or quiet
+}
+
+// mpvArgs returns the mpv arguments for the settings; the volume is handled by PlayOptions
+func (s *StreamSettings) mpvArgs() []string {
+	if s == nil {
+		return nil
+	}
+	var args []string
+	if s.UserAgent != "" {
+		args = append(args, "--user-agent="+s.UserAgent)
+	}
+	if s.Referer != "" {
+		args = append(args, "--referrer="+s.Referer)
+	}
+	if s.Demuxer != "" {
+		args = append(args, "--demuxer-lavf-format="+s.Demuxer)
+	}
+	if s.CacheSeconds > 0 {
+		args = append(args, "--cache=yes",
+			fmt.Sprintf("--cache-secs=%d", s.CacheSeconds),
+			fmt.Sprintf("--demuxer-readahead-secs=%d", s.CacheSeconds))
+	}
+	return args
+}
+
+// setHeaders applies the User-Agent and referer to a request made on the station's behalf
+func (s *StreamSettings) setHeaders(req *http.Request) {
+	if s == nil {
+		return
+	}
+	if s.UserAgent != "" {
+		req.Header.Set("User-Agent", s.UserAgent)
+	}
+	if s.Referer != "" {
+		req.Header.Set("Referer", s.Referer)
+	}
+}
+
+// Overrides describes each setting in effect, for the station info panel
+func (s *StreamSettings) Overrides() []string {
+	if s == nil {
+		return nil
+	}
+	var lines []string
+	if s.UserAgent != "" {
+		lines = append(lines, "User-Agent: "+s.UserAgent)
+	}
+	if s.Referer != "" {
+		lines = append(lines, "Referer: "+s.Referer)
+	}
+	if s.Demuxer != "" {
+		lines = append(lines, "Demuxer: "+s.Demuxer)
+	}
+	if s.CacheSeconds > 0 {
+		lines = append(lines, fmt.Sprintf("Cache: %ds", s.CacheSeconds))
+	}
+	if s.VolumeOffset != 0 {
+		lines = append(lines, fmt.Sprintf("Volume: %+d", s.VolumeOffset))
+	}
+	return lines
+}
+
+// validate returns what is wrong with the settings, if anything
+func (s *StreamSettings) validate() []string {
+	if s == nil {
+		return nil
+	}
+	var problems []string
+	if s.Referer != "" && checkStreamURL(s.Referer) != "" {
+		problems = append(problems, fmt.Sprintf("referer %q must be an http(s) URL", s.Referer))
+	}
+	if strings.ContainsAny(s.Demuxer, " /") {
+		problems = append(problems, fmt.Sprintf("demuxer %q must be a format name such as \"aac\"", s.Demuxer))
+	}
+	if s.CacheSeconds < 0 {
+		problems = append(problems, "cache_seconds must not be negative")
+	}
+	if s.VolumeOffset < -maxVolume || s.VolumeOffset > maxVolume {
+		problems = append(problems, fmt.Sprintf("volume_offset must be between -%d and %d", maxVolume, maxVolume))
+	}
+	return problems
+}
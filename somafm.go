@@ -0,0 +1,349 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultSomaFMCatalogURL lists every SomaFM channel; somafm_url in config.json overrides it
+const defaultSomaFMCatalogURL = "https://api.somafm.com/channels.json"
+
+// defaultSomaFMRefresh is how often the TUI refreshes the catalog in the background
+const defaultSomaFMRefresh = 24 * time.Hour
+
+// somaFMChannel is one entry of the SomaFM channels.json catalog
+type somaFMChannel struct {
+	ID          string           `json:"id"`
+	Title       string           `json:"title"`
+	Description string           `json:"description"`
+	Genre       string           `json:"genre"` // Pipe separated, e.g. "ambient|electronica"
+	Image       string           `json:"image"`
+	LargeImage  string           `json:"largeimage"`
+	Listeners   string           `json:"listeners"`
+	Playlists   []somaFMPlaylist `json:"playlists"`
+}
+
+// somaFMPlaylist is a PLS link for one encoding of a channel
+type somaFMPlaylist struct {
+	URL     string `json:"url"`
+	Format  string `json:"format"`  // mp3, aac or aacp
+	Quality string `json:"quality"` // highest, high or low
+}
+
+// somaFMCache is the last good catalog, kept for conditional requests and offline use
+type somaFMCache struct {
+	ETag      string          `json:"etag,omitempty"`
+	FetchedAt time.Time       `json:"fetched_at"`
+	Channels  []somaFMChannel `json:"channels"`
+}
+
+// SomaFMSyncResult describes the outcome of a catalog refresh
+type SomaFMSyncResult struct {
+	Channels  int
+	Unchanged bool      // The server answered 304 Not Modified
+	Offline   bool      // The fetch failed and the cached copy is in use
+	FetchedAt time.Time // When the catalog in use was downloaded
+	Err       error     // Why the fetch failed, when Offline
+}
+
+func (r SomaFMSyncResult) String() string {
+	switch {
+	case r.Offline && r.Channels > 0:
+		return fmt.Sprintf("SomaFM sync failed (%v); using %d channels from %s", r.Err, r.Channels, r.FetchedAt.Format("2006-01-02 15:04"))
+	case r.Offline:
+		return fmt.Sprintf("SomaFM sync failed: %v", r.Err)
+	case r.Unchanged:
+		return fmt.Sprintf("SomaFM catalog unchanged (%d channels)", r.Channels)
+	}
+	return fmt.Sprintf("SomaFM catalog updated (%d channels)", r.Channels)
+}
+
+// somaFMCachePath is where the last good catalog is stored
+func somaFMCachePath() (string, error) {
+	dir, err := CacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "somafm-channels.json"), nil
+}
+
+// loadSomaFMCache reads the cached catalog; a missing cache is not an error
+func loadSomaFMCache() (*somaFMCache, error) {
+	path, err := somaFMCachePath()
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var cache somaFMCache
+	if err := json.Unmarshal(data, &cache); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return &cache, nil
+}
+
+// saveSomaFMCache stores the catalog as the last good copy
+func saveSomaFMCache(cache *somaFMCache) error {
+	path, err := somaFMCachePath()
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(cache, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(path, append(data, '\n'), 0644)
+}
+
+// SomaFMCatalogAge reports when the cached catalog was fetched, or the zero time if never
+func SomaFMCatalogAge() time.Time {
+	cache, err := loadSomaFMCache()
+	if err != nil || cache == nil {
+		return time.Time{}
+	}
+	return cache.FetchedAt
+}
+
+// SyncSomaFM downloads the channel catalog into the cache. The cached ETag is
+// sent along so an unchanged catalog costs a 304; when the server cannot be
+// reached the last good copy stays in use and the result says so.
+func SyncSomaFM(catalogURL string) SomaFMSyncResult {
+	if catalogURL == "" {
+		catalogURL = defaultSomaFMCatalogURL
+	}
+
+	cache, err := loadSomaFMCache()
+	if err != nil {
+		log.Printf("somafm: ignoring unreadable cache: %v", err)
+		cache = nil
+	}
+	offline := func(err error) SomaFMSyncResult {
+		result := SomaFMSyncResult{Offline: true, Err: err}
+		if cache != nil {
+			result.Channels = len(cache.Channels)
+			result.FetchedAt = cache.FetchedAt
+		}
+		log.Printf("somafm: %v", err)
+		return result
+	}
+
+	req, err := http.NewRequest("GET", catalogURL, nil)
+	if err != nil {
+		return offline(err)
+	}
+	req.Header.Set("User-Agent", "goradio-hub/"+Version)
+	if cache != nil && cache.ETag != "" {
+		req.Header.Set("If-None-Match", cache.ETag)
+	}
+
+	client := &http.Client{Timeout: directoryTimeout}
+	resp, err := client.Do(req)
+	if err != nil {
+		return offline(err)
+	}
+	defer resp.Body.Close()
+
+	now := time.Now()
+	switch {
+	case resp.StatusCode == http.StatusNotModified && cache != nil:
+		cache.FetchedAt = now
+		if err := saveSomaFMCache(cache); err != nil {
+			log.Printf("somafm: saving cache: %v", err)
+		}
+		return SomaFMSyncResult{Channels: len(cache.Channels), Unchanged: true, FetchedAt: now}
+	case resp.StatusCode != http.StatusOK:
+		return offline(fmt.Errorf("%s answered %s", catalogURL, resp.Status))
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return offline(err)
+	}
+	var catalog struct {
+		Channels []somaFMChannel `json:"channels"`
+	}
+	if err := json.Unmarshal(body, &catalog); err != nil {
+		return offline(fmt.Errorf("decoding catalog: %v", err))
+	}
+	if len(catalog.Channels) == 0 {
+		// Never replace a good copy with an empty one
+		return offline(fmt.Errorf("catalog lists no channels"))
+	}
+
+	fresh := &somaFMCache{ETag: resp.Header.Get("ETag"), FetchedAt: now, Channels: catalog.Channels}
+	if err := saveSomaFMCache(fresh); err != nil {
+		return offline(fmt.Errorf("saving cache: %v", err))
+	}
+	return SomaFMSyncResult{Channels: len(fresh.Channels), FetchedAt: now}
+}
+
+// BuiltinStations returns the compiled-in stations refreshed from the cached
+// SomaFM catalog: known channels get current descriptions, streams and listener
+// counts while keeping their names and genres, and new channels are appended.
+func BuiltinStations() []RadioStation {
+	stations := DefaultStations()
+	cache, err := loadSomaFMCache()
+	if err != nil {
+		log.Printf("somafm: %v", err)
+	}
+	if cache == nil {
+		return stations
+	}
+	return applySomaFMCatalog(stations, cache.Channels)
+}
+
+// applySomaFMCatalog merges catalog channels into the built-in stations
+func applySomaFMCatalog(stations []RadioStation, channels []somaFMChannel) []RadioStation {
+	byChannel := make(map[string]int)
+	for i, s := range stations {
+		if id := somaFMChannelID(s.URL); id != "" {
+			byChannel[id] = i
+		}
+	}
+
+	for _, ch := range channels {
+		streams := ch.streams()
+		if ch.ID == "" || len(streams) == 0 {
+			continue
+		}
+		listeners, _ := strconv.Atoi(ch.Listeners)
+
+		if i, ok := byChannel[ch.ID]; ok {
+			s := &stations[i]
+			if !hasStream(streams, s.URL) {
+				s.URL = SelectStream(&RadioStation{Streams: streams}, QualityHigh).URL
+			}
+			s.Streams = streams
+			s.Listeners = listeners
+			s.Tags = NormalizeTags(append(s.Tags, ch.genres()...))
+			if ch.Description != "" {
+				s.Description = ch.Description
+			}
+			if s.Logo == "" {
+				s.Logo = ch.logo()
+			}
+			continue
+		}
+
+		genres := ch.genres()
+		station := RadioStation{
+			Name:        ch.Title,
+			Description: ch.Description,
+			Streams:     streams,
+			Mirrors:     somaFMMirrors,
+			Listeners:   listeners,
+			Logo:        ch.logo(),
+		}
+		if len(genres) > 0 {
+			station.Genre, station.Tags = genres[0], genres[1:]
+		}
+		station.URL = SelectStream(&station, QualityHigh).URL
+		stations = append(stations, station)
+	}
+	return stations
+}
+
+// logo returns the channel's largest square image
+func (ch somaFMChannel) logo() string {
+	if ch.LargeImage != "" {
+		return ch.LargeImage
+	}
+	return ch.Image
+}
+
+// genres splits the catalog's pipe separated genre field into normalized tags
+func (ch somaFMChannel) genres() []string {
+	return NormalizeTags(strings.Split(ch.Genre, "|"))
+}
+
+// hasStream reports whether url is one of the variants
+func hasStream(streams []StreamVariant, url string) bool {
+	for _, v := range streams {
+		if v.URL == url {
+			return true
+		}
+	}
+	return false
+}
+
+// somaFMChannelURL matches SomaFM direct stream URLs such as https://ice1.somafm.com/groovesalad-128-mp3
+var somaFMChannelURL = regexp.MustCompile(`^https?://ice\d*\.somafm\.com/([a-z0-9]+)-\d+-(?:mp3|aac)$`)
+
+// somaFMChannelID extracts the channel from a SomaFM stream URL, or "" for other stations
+func somaFMChannelID(streamURL string) string {
+	if m := somaFMChannelURL.FindStringSubmatch(streamURL); m != nil {
+		return m[1]
+	}
+	return ""
+}
+
+// streams turns the channel's playlist links into direct stream variants, using
+// the same ice1 URLs as somaFMStreams so mirror failover keeps working
+func (ch somaFMChannel) streams() []StreamVariant {
+	seen := make(map[string]bool)
+	var streams []StreamVariant
+	for _, p := range ch.Playlists {
+		bitrate := somaFMBitrate(ch.ID, p)
+		if bitrate == 0 {
+			continue
+		}
+		suffix := "aac"
+		if p.Format == "mp3" {
+			suffix = "mp3"
+		}
+		url := fmt.Sprintf("https://ice1.somafm.com/%s-%d-%s", ch.ID, bitrate, suffix)
+		if seen[url] {
+			continue
+		}
+		seen[url] = true
+		streams = append(streams, StreamVariant{URL: url, Codec: p.Format, Bitrate: bitrate})
+	}
+
+	// Highest bitrate first, MP3 before AAC at the same rate, like somaFMStreams
+	codecOrder := map[string]int{"mp3": 0, "aac": 1, "aacp": 2}
+	sort.SliceStable(streams, func(i, j int) bool {
+		if streams[i].Bitrate != streams[j].Bitrate {
+			return streams[i].Bitrate > streams[j].Bitrate
+		}
+		return codecOrder[streams[i].Codec] < codecOrder[streams[j].Codec]
+	})
+	return streams
+}
+
+// somaFMBitrate works out a playlist's bitrate. The catalog does not state it, but
+// the PLS names do: groovesalad256.pls, groovesalad64.pls; "130" is SomaFM's name
+// for 128k AAC, and an MP3 playlist without a number is 128k.
+func somaFMBitrate(channel string, p somaFMPlaylist) int {
+	name := strings.TrimSuffix(path.Base(p.URL), ".pls")
+	digits := strings.TrimPrefix(name, channel)
+	if digits == "" {
+		if p.Format == "mp3" {
+			return 128
+		}
+		return 0
+	}
+	bitrate, err := strconv.Atoi(digits)
+	if err != nil {
+		return 0
+	}
+	if bitrate == 130 {
+		return 128
+	}
+	return bitrate
+}
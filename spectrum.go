@@ -0,0 +1,316 @@
+package main
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"math/cmplx"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+const (
+	// spectrumRate is the sample rate the PCM tap decodes to; plenty for a visualizer
+	spectrumRate = 22050
+	// spectrumFFTSize is the number of samples per FFT frame (~46ms at spectrumRate)
+	spectrumFFTSize = 1024
+	// spectrumBands is how many bars the visualizer draws
+	spectrumBands = 24
+	// spectrumFrameInterval is how often the UI redraws the bars (~25 fps)
+	spectrumFrameInterval = 40 * time.Millisecond
+	// spectrumStale is how long after the last samples the bars are still shown
+	spectrumStale = time.Second
+	// spectrumBacklog is how many decoded frames (~46ms each) may wait to be shown
+	spectrumBacklog = 1024
+	// spectrumDecay controls how quickly bars fall after a peak
+	spectrumDecay = 0.85
+	// spectrumFloorDB is the level drawn as an empty bar
+	spectrumFloorDB = -60.0
+)
+
+// pcmTap decodes the audio mpv is playing to PCM with ffmpeg and turns it into spectrum bars.
+// mpv records the stream it has already downloaded into a FIFO, so the station
+// is not fetched a second time.
+type pcmTap struct {
+	cmd  *exec.Cmd
+	proc *mpvProcess
+	fifo string
+	hold *os.File // Write end kept open so ffmpeg does not see the FIFO end before mpv opens it
+
+	mu      sync.Mutex
+	bars    []float64
+	updated time.Time
+}
+
+var tapFIFOSeq int64
+
+// tapFIFOPath returns a unique path for a new tap's FIFO. The extension picks
+// the container mpv records into.
+func tapFIFOPath() string {
+	n := atomic.AddInt64(&tapFIFOSeq, 1)
+	return filepath.Join(RuntimeDir(), fmt.Sprintf("goradio-tap-%d-%d.mka", os.Getpid(), n))
+}
+
+// startPCMTap has mpv record its stream into a FIFO and launches ffmpeg to
+// decode it to mono 16-bit PCM at spectrumRate. lead is how far mpv's reading
+// is ahead of what it plays, so the bars can wait for the audio they belong to.
+func startPCMTap(proc *mpvProcess, lead time.Duration) (*pcmTap, error) {
+	if _, err := exec.LookPath("ffmpeg"); err != nil {
+		return nil, fmt.Errorf("ffmpeg not found, visualizer disabled")
+	}
+
+	fifo := tapFIFOPath()
+	os.Remove(fifo)
+	if err := syscall.Mkfifo(fifo, 0600); err != nil {
+		return nil, err
+	}
+	// Opening the read end without blocking lets the write ends open at once
+	r, err := os.OpenFile(fifo, os.O_RDONLY|syscall.O_NONBLOCK, 0)
+	if err != nil {
+		os.Remove(fifo)
+		return nil, err
+	}
+	defer r.Close()
+	// ffmpeg reads it blocking, like any other stdin
+	if err := syscall.SetNonblock(int(r.Fd()), false); err != nil {
+		os.Remove(fifo)
+		return nil, err
+	}
+	hold, err := os.OpenFile(fifo, os.O_WRONLY, 0)
+	if err != nil {
+		os.Remove(fifo)
+		return nil, err
+	}
+
+	cmd := exec.Command("ffmpeg", "-loglevel", "quiet", "-f", "matroska", "-i", "pipe:0",
+		"-vn", "-ac", "1", "-ar", fmt.Sprint(spectrumRate), "-f", "s16le", "-")
+	cmd.Stdin = r
+	stdout, err := cmd.StdoutPipe()
+	if err == nil {
+		err = cmd.Start()
+	}
+	if err != nil {
+		hold.Close()
+		os.Remove(fifo)
+		return nil, err
+	}
+
+	t := &pcmTap{cmd: cmd, proc: proc, fifo: fifo, hold: hold, bars: make([]float64, spectrumBands)}
+	if err := proc.setProperty("stream-record", fifo); err != nil {
+		t.stop()
+		return nil, fmt.Errorf("recording the stream for the visualizer: %v", err)
+	}
+	go t.readLoop(stdout, lead)
+	return t, nil
+}
+
+// readLoop reads PCM frames and updates the bars until ffmpeg exits. Frames
+// arrive as fast as mpv downloads them and are shown at the pace they play.
+func (t *pcmTap) readLoop(r io.Reader, lead time.Duration) {
+	defer t.cmd.Wait()
+
+	frames := make(chan []float64, spectrumBacklog)
+	go func() {
+		defer close(frames)
+		raw := make([]byte, spectrumFFTSize*2)
+		for {
+			if _, err := io.ReadFull(r, raw); err != nil {
+				return
+			}
+			samples := make([]float64, spectrumFFTSize)
+			for i := range samples {
+				samples[i] = float64(int16(binary.LittleEndian.Uint16(raw[i*2:]))) / 32768
+			}
+			frames <- samples
+		}
+	}()
+
+	start := time.Now().Add(lead)
+	frameTime := time.Second * spectrumFFTSize / spectrumRate
+	for k := 0; ; k++ {
+		samples, ok := <-frames
+		if !ok {
+			return
+		}
+		// Frames that are late, such as the ones held back while ffmpeg probes the stream, are shown at once
+		if wait := time.Until(start.Add(time.Duration(k) * frameTime)); wait > 0 {
+			time.Sleep(wait)
+		}
+
+		bands := spectrumBandLevels(samples)
+
+		t.mu.Lock()
+		for i, level := range bands {
+			// Rise instantly, fall smoothly
+			t.bars[i] = math.Max(level, t.bars[i]*spectrumDecay)
+		}
+		t.updated = time.Now()
+		t.mu.Unlock()
+	}
+}
+
+// Bars returns the current bar levels (0..1) and whether samples arrived recently
+func (t *pcmTap) Bars() ([]float64, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if time.Since(t.updated) > spectrumStale {
+		return nil, false
+	}
+	return append([]float64(nil), t.bars...), true
+}
+
+// stop ends the recording and terminates the decoder
+func (t *pcmTap) stop() {
+	if !t.proc.exited() {
+		t.proc.setProperty("stream-record", "")
+	}
+	if t.cmd.Process != nil {
+		t.cmd.Process.Kill()
+	}
+	t.hold.Close()
+	os.Remove(t.fifo)
+}
+
+// spectrumBandLevels windows a frame, runs an FFT and folds the bins into log-spaced bands
+func spectrumBandLevels(samples []float64) []float64 {
+	n := len(samples)
+	buf := make([]complex128, n)
+	for i, s := range samples {
+		// Hann window to reduce spectral leakage
+		w := 0.5 * (1 - math.Cos(2*math.Pi*float64(i)/float64(n-1)))
+		buf[i] = complex(s*w, 0)
+	}
+	fft(buf)
+
+	const lowHz, highHz = 40.0, 10000.0
+	binHz := float64(spectrumRate) / float64(n)
+	levels := make([]float64, spectrumBands)
+
+	for b := 0; b < spectrumBands; b++ {
+		from := lowHz * math.Pow(highHz/lowHz, float64(b)/spectrumBands)
+		to := lowHz * math.Pow(highHz/lowHz, float64(b+1)/spectrumBands)
+		lo := int(from / binHz)
+		hi := int(to / binHz)
+		if hi <= lo {
+			hi = lo + 1
+		}
+
+		peak := 0.0
+		for k := lo; k < hi && k < n/2; k++ {
+			peak = math.Max(peak, cmplx.Abs(buf[k]))
+		}
+
+		// Normalize against a full-scale sine through a Hann window
+		db := 20 * math.Log10(peak/(float64(n)/4)+1e-12)
+		level := (db - spectrumFloorDB) / -spectrumFloorDB
+		levels[b] = math.Max(0, math.Min(1, level))
+	}
+	return levels
+}
+
+// fft performs an in-place iterative radix-2 FFT; len(a) must be a power of two
+func fft(a []complex128) {
+	n := len(a)
+
+	// Bit-reversal permutation
+	for i, j := 1, 0; i < n; i++ {
+		bit := n >> 1
+		for ; j&bit != 0; bit >>= 1 {
+			j ^= bit
+		}
+		j ^= bit
+		if i < j {
+			a[i], a[j] = a[j], a[i]
+		}
+	}
+
+	for size := 2; size <= n; size <<= 1 {
+		step := cmplx.Exp(complex(0, -2*math.Pi/float64(size)))
+		for start := 0; start < n; start += size {
+			w := complex(1, 0)
+			for k := 0; k < size/2; k++ {
+				u := a[start+k]
+				v := a[start+k+size/2] * w
+				a[start+k] = u + v
+				a[start+k+size/2] = u - v
+				w *= step
+			}
+		}
+	}
+}
+
+// startTap begins feeding the visualizer from the audio proc plays, replacing any previous tap
+func (p *Player) startTap(proc *mpvProcess, session int) {
+	p.stopTap()
+	p.mu.Lock()
+	visualizer := p.opts.Visualizer
+	p.mu.Unlock()
+	if !visualizer {
+		return
+	}
+
+	// Unavailable while mpv is still opening the stream, when nothing is read ahead yet
+	var lead float64
+	proc.getProperty("demuxer-cache-duration", &lead)
+	tap, err := startPCMTap(proc, time.Duration(lead*float64(time.Second)))
+	if err != nil {
+		log.Printf("visualizer: %v", err)
+		return
+	}
+	if _, current := p.sessionStation(session); !current {
+		tap.stop()
+		return
+	}
+	p.tapMu.Lock()
+	p.tap = tap
+	p.tapMu.Unlock()
+}
+
+// stopTap shuts down the visualizer decoder if one is running
+func (p *Player) stopTap() {
+	p.tapMu.Lock()
+	tap := p.tap
+	p.tap = nil
+	p.tapMu.Unlock()
+	if tap != nil {
+		tap.stop()
+	}
+}
+
+// GetSpectrum returns the latest spectrum bars, or false when no samples are available
+func (p *Player) GetSpectrum() ([]float64, bool) {
+	p.tapMu.Lock()
+	tap := p.tap
+	p.tapMu.Unlock()
+	if tap == nil {
+		return nil, false
+	}
+	return tap.Bars()
+}
+
+// spectrumBlocks are the bar heights from silent to full
+var spectrumBlocks = []rune(" ▁▂▃▄▅▆▇█")
+
+// RenderSpectrum draws spectrum bars as a single row of block characters
+func RenderSpectrum(bars []float64) string {
+	var b strings.Builder
+	for _, level := range bars {
+		idx := int(level * float64(len(spectrumBlocks)-1))
+		if idx < 0 {
+			idx = 0
+		}
+		if idx >= len(spectrumBlocks) {
+			idx = len(spectrumBlocks) - 1
+		}
+		b.WriteRune(spectrumBlocks[idx])
+	}
+	return b.String()
+}
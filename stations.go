@@ -1,77 +1,184 @@
 package main
 
-import "fmt"
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+	"unicode"
+)
 
 // RadioStation represents a radio station with its metadata
 type RadioStation struct {
-	Name        string
-	URL         string
-	Genre       string
-	Description string
+	ID          string          `json:"id,omitempty"` // Stable key; derived from Name when empty
+	Name        string          `json:"name"`
+	URL         string          `json:"url"`
+	Genre       string          `json:"genre,omitempty"` // Primary tag, shown in the list
+	Tags        []string        `json:"tags,omitempty"`  // Further tags; see NormalizeTag
+	Description string          `json:"description,omitempty"`
+	Streams     []StreamVariant `json:"streams,omitempty"`  // Alternative encodings; URL is used when empty
+	Mirrors     []string        `json:"mirrors,omitempty"`  // Hosts serving the same stream paths, tried when the main server fails
+	Settings    *StreamSettings `json:"settings,omitempty"` // Playback tweaks for streams that need them
+	Logo        string          `json:"logo,omitempty"`     // Image URL or local path shown in the info panel
+	Homepage    string          `json:"homepage,omitempty"` // The station's website
+	Listeners   int             `json:"-"`                  // Current audience, when a catalog reports it
+	Favorite    bool            `json:"-"`                  // Set from the favorites list when stations are listed
+	LastPlayed  time.Time       `json:"-"`                  // Set from the play history when stations are listed
+	Listened    time.Duration   `json:"-"`                  // Total listening time, from the play history
+	Rating      int             `json:"-"`                  // The user's 1-5 rating, 0 when unrated
+	Health      HealthStatus    `json:"-"`                  // Last availability check, "" when never checked
+	Uptime      float64         `json:"-"`                  // Share of last week's checks that found it playing
+	Checks      int             `json:"-"`                  // How many checks Uptime is based on
 }
 
-// GetStations returns all available radio stations
+// StreamVariant is one encoding of a station's stream
+type StreamVariant struct {
+	URL     string `json:"url"`
+	Codec   string `json:"codec,omitempty"`   // mp3, aac, aacp, ogg
+	Bitrate int    `json:"bitrate,omitempty"` // kbps
+}
+
+// Key returns a stable identifier for the station used to store per-station preferences
+func (s *RadioStation) Key() string {
+	if s.ID != "" {
+		return s.ID
+	}
+	return nameKey(s.Name)
+}
+
+// nameKey is the key of a station without an id: its name lowercased, with
+// dashes between words. Preferences are stored under these keys, so it keeps
+// punctuation rather than change the key of an existing station.
+func nameKey(name string) string {
+	return strings.ToLower(strings.Join(strings.Fields(name), "-"))
+}
+
+// slugify lowercases a name and joins its words with dashes, dropping punctuation.
+// It makes ids for new stations; names without letters or digits give "".
+func slugify(name string) string {
+	words := strings.FieldsFunc(strings.ToLower(name), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	return strings.Join(words, "-")
+}
+
+// somaFMStreams lists the standard SomaFM encodings for a channel.
+// Only a few channels carry the 256k MP3 stream, so it is opt-in.
+func somaFMStreams(channel string, with256 bool) []StreamVariant {
+	base := "https://ice1.somafm.com/" + channel
+	streams := []StreamVariant{
+		{URL: base + "-128-mp3", Codec: "mp3", Bitrate: 128},
+		{URL: base + "-128-aac", Codec: "aac", Bitrate: 128},
+		{URL: base + "-64-aac", Codec: "aacp", Bitrate: 64},
+		{URL: base + "-32-aac", Codec: "aacp", Bitrate: 32},
+	}
+	if with256 {
+		streams = append([]StreamVariant{{URL: base + "-256-mp3", Codec: "mp3", Bitrate: 256}}, streams...)
+	}
+	return streams
+}
+
+// GetStations returns all available radio stations: the built-in defaults merged with
+// the user's station file. Problems with the file are logged and the defaults are used.
 func GetStations() []RadioStation {
+	stations, err := LoadStations()
+	if err != nil {
+		log.Printf("stations: %v", err)
+	}
+	return stations
+}
+
+// DefaultStations returns the station list compiled into the binary
+func DefaultStations() []RadioStation {
 	return []RadioStation{
 		// VERIFIED WORKING SomaFM Stations - Direct MP3 URLs
 		{
 			Name:        "Groove Salad",
 			URL:         "https://ice1.somafm.com/groovesalad-128-mp3",
+			Streams:     somaFMStreams("groovesalad", true),
+			Mirrors:     somaFMMirrors,
 			Genre:       "Downtempo",
+			Tags:        []string{"Ambient", "Electronic"},
 			Description: "Chilled ambient/downtempo beats and grooves",
 		},
 		{
 			Name:        "Drone Zone", 
 			URL:         "https://ice1.somafm.com/dronezone-128-mp3",
+			Streams:     somaFMStreams("dronezone", false),
+			Mirrors:     somaFMMirrors,
 			Genre:       "Ambient",
+			Tags:        []string{"Electronic"},
 			Description: "Deep ambient soundscapes for meditation",
 		},
 		{
 			Name:        "Lush",
 			URL:         "https://ice1.somafm.com/lush-128-mp3",
+			Streams:     somaFMStreams("lush", false),
+			Mirrors:     somaFMMirrors,
 			Genre:       "Dream Pop",
 			Description: "Sensuous vocals with electronic influence",
 		},
 		{
 			Name:        "Space Station",
 			URL:         "https://ice1.somafm.com/spacestation-128-mp3",
+			Streams:     somaFMStreams("spacestation", false),
+			Mirrors:     somaFMMirrors,
 			Genre:       "Space Ambient",
+			Tags:        []string{"Ambient", "Electronic"},
 			Description: "Spaced-out ambient electronica",
 		},
 		{
 			Name:        "Fluid",
 			URL:         "https://ice1.somafm.com/fluid-128-mp3",
+			Streams:     somaFMStreams("fluid", false),
+			Mirrors:     somaFMMirrors,
 			Genre:       "Future Soul",
+			Tags:        []string{"Hip Hop", "Soul"},
 			Description: "Instrumental hiphop and liquid trap",
 		},
 		{
 			Name:        "Beat Blender",
 			URL:         "https://ice1.somafm.com/beatblender-128-mp3",
+			Streams:     somaFMStreams("beatblender", false),
+			Mirrors:     somaFMMirrors,
 			Genre:       "Deep House",
+			Tags:        []string{"Electronic"},
 			Description: "Late night deep-house and downtempo chill",
 		},
 		{
 			Name:        "Vaporwaves",
 			URL:         "https://ice1.somafm.com/vaporwaves-128-mp3",
+			Streams:     somaFMStreams("vaporwaves", false),
+			Mirrors:     somaFMMirrors,
 			Genre:       "Vaporwave",
+			Tags:        []string{"Synthwave", "Electronic"},
 			Description: "Aesthetic vaporwave and future funk",
 		},
 		{
 			Name:        "Underground 80s",
 			URL:         "https://ice1.somafm.com/u80s-128-mp3",
+			Streams:     somaFMStreams("u80s", false),
+			Mirrors:     somaFMMirrors,
 			Genre:       "Synthpop",
+			Tags:        []string{"80s", "New Wave"},
 			Description: "Early 80s UK synthpop and new wave",
 		},
 		{
 			Name:        "DEF CON Radio",
 			URL:         "https://ice1.somafm.com/defcon-128-mp3",
+			Streams:     somaFMStreams("defcon", false),
+			Mirrors:     somaFMMirrors,
 			Genre:       "Hacker",
+			Tags:        []string{"Electronic"},
 			Description: "Music for hacking - DEF CON vibes",
 		},
 		{
 			Name:        "Secret Agent",
 			URL:         "https://ice1.somafm.com/secretagent-128-mp3",
+			Streams:     somaFMStreams("secretagent", false),
+			Mirrors:     somaFMMirrors,
 			Genre:       "Spy Jazz",
+			Tags:        []string{"Jazz", "Downtempo"},
 			Description: "The soundtrack for your stylish life",
 		},
 		
@@ -80,70 +187,92 @@ func GetStations() []RadioStation {
 			Name:        "Plaza Radio",
 			URL:         "https://radio.plaza.one/ogg",
 			Genre:       "Vaporwave",
+			Tags:        []string{"Synthwave"},
 			Description: "24/7 vaporwave, synthwave, and aesthetic",
 		},
 		{
 			Name:        "Bassdrive DNB",
 			URL:         "http://bassdrive.com/bassdrive3.m3u",
 			Genre:       "Drum & Bass",
+			Tags:        []string{"Electronic"},
 			Description: "World's largest drum & bass station",
 		},
 		{
 			Name:        "ChillHop Radio",
 			URL:         "http://stream.laut.fm/chillhop",
 			Genre:       "Lofi Hip Hop",
+			Tags:        []string{"Hip Hop", "Lo-Fi"},
 			Description: "24/7 chillhop and lofi hip hop beats",
 		},
 		{
 			Name:        "Deep Space One",
 			URL:         "https://ice1.somafm.com/deepspaceone-128-mp3",
+			Streams:     somaFMStreams("deepspaceone", false),
+			Mirrors:     somaFMMirrors,
 			Genre:       "Space Music",
+			Tags:        []string{"Ambient"},
 			Description: "Deep ambient electronic space music",
 		},
 		{
 			Name:        "Boot Liquor",
 			URL:         "https://ice1.somafm.com/bootliquor-128-mp3",
+			Streams:     somaFMStreams("bootliquor", false),
+			Mirrors:     somaFMMirrors,
 			Genre:       "Americana",
 			Description: "Roots music for cowpokes and indie rockers",
 		},
 		{
 			Name:        "Cliqhop IDM",
 			URL:         "https://ice1.somafm.com/cliqhop-128-mp3",
+			Streams:     somaFMStreams("cliqhop", false),
+			Mirrors:     somaFMMirrors,
 			Genre:       "IDM",
+			Tags:        []string{"Electronic"},
 			Description: "Blips, beeps and clicks of intelligent dance",
 		},
 		{
 			Name:        "The Trip",
 			URL:         "https://ice1.somafm.com/thetrip-128-mp3",
+			Streams:     somaFMStreams("thetrip", false),
+			Mirrors:     somaFMMirrors,
 			Genre:       "Psychedelic",
+			Tags:        []string{"Electronic"},
 			Description: "Progressive rock and trippy experimental music",
 		},
 		{
 			Name:        "Seven Inch Soul",
 			URL:         "https://ice1.somafm.com/7soul-128-mp3",
+			Streams:     somaFMStreams("7soul", false),
+			Mirrors:     somaFMMirrors,
 			Genre:       "Soul/R&B",
+			Tags:        []string{"Soul", "R&B"},
 			Description: "Vintage soul tracks from original 45 RPM vinyl",
 		},
 		{
 			Name:        "Metal Detector",
 			URL:         "https://ice1.somafm.com/metal-128-mp3",
+			Streams:     somaFMStreams("metal", false),
+			Mirrors:     somaFMMirrors,
 			Genre:       "Metal",
 			Description: "From black to doom, thrash to post-metal",
 		},
 		{
 			Name:        "Folk Forward",
 			URL:         "https://ice1.somafm.com/folkfwd-128-mp3",
+			Streams:     somaFMStreams("folkfwd", false),
+			Mirrors:     somaFMMirrors,
 			Genre:       "Folk",
 			Description: "Indie folk, alt-folk and folk classics",
 		},
 	}
 }
 
-// GetStationsByGenre returns stations filtered by genre
+// GetStationsByGenre returns stations tagged with genre, as primary genre or
+// as a further tag; the match ignores case and understands aliases
 func GetStationsByGenre(genre string) []RadioStation {
 	var filtered []RadioStation
 	for _, station := range GetStations() {
-		if station.Genre == genre {
+		if station.HasTag(genre) {
 			filtered = append(filtered, station)
 		}
 	}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"sort"
+	"strings"
+)
+
+// tagAliases maps common spellings (already lowercased) onto one canonical tag
+var tagAliases = map[string]string{
+	"dnb":           "Drum & Bass",
+	"d&b":           "Drum & Bass",
+	"d'n'b":         "Drum & Bass",
+	"drum and bass": "Drum & Bass",
+	"drum n bass":   "Drum & Bass",
+	"drum'n'bass":   "Drum & Bass",
+	"drumnbass":     "Drum & Bass",
+	"hiphop":        "Hip Hop",
+	"hip-hop":       "Hip Hop",
+	"lofi":          "Lo-Fi",
+	"lo fi":         "Lo-Fi",
+	"lofi hip hop":  "Lofi Hip Hop",
+	"lofi hiphop":   "Lofi Hip Hop",
+	"lo-fi hip hop": "Lofi Hip Hop",
+	"rnb":           "R&B",
+	"r'n'b":         "R&B",
+	"r and b":       "R&B",
+	"idm":           "IDM",
+	"electronica":   "Electronic",
+	"electro":       "Electronic",
+	"retrowave":     "Synthwave",
+	"outrun":        "Synthwave",
+	"eighties":      "80s",
+	"1980s":         "80s",
+	"downbeat":      "Downtempo",
+	"chill out":     "Chillout",
+	"chill-out":     "Chillout",
+}
+
+// tagKey is the case- and spacing-insensitive form tags are compared by
+func tagKey(tag string) string {
+	return strings.ToLower(strings.Join(strings.Fields(tag), " "))
+}
+
+// NormalizeTag returns the canonical spelling of a tag: aliases are resolved,
+// whitespace is collapsed and words are capitalized. It returns "" for blank tags.
+func NormalizeTag(tag string) string {
+	key := tagKey(tag)
+	if key == "" {
+		return ""
+	}
+	if canonical, ok := tagAliases[key]; ok {
+		return canonical
+	}
+	return titleCase(strings.Join(strings.Fields(tag), " "))
+}
+
+// NormalizeTags normalizes a list of tags, dropping blanks and duplicates
+func NormalizeTags(tags []string) []string {
+	seen := make(map[string]bool)
+	var out []string
+	for _, tag := range tags {
+		tag = NormalizeTag(tag)
+		if tag == "" || seen[tagKey(tag)] {
+			continue
+		}
+		seen[tagKey(tag)] = true
+		out = append(out, tag)
+	}
+	return out
+}
+
+// splitTags parses a comma separated tag list as typed by the user
+func splitTags(s string) []string {
+	return NormalizeTags(strings.Split(s, ","))
+}
+
+// AllTags returns the station's genre followed by its other tags, normalized
+func (s *RadioStation) AllTags() []string {
+	return NormalizeTags(append([]string{s.Genre}, s.Tags...))
+}
+
+// HasTag reports whether the station's genre or tags include tag, after normalization
+func (s *RadioStation) HasTag(tag string) bool {
+	want := tagKey(NormalizeTag(tag))
+	for _, t := range s.AllTags() {
+		if tagKey(t) == want {
+			return true
+		}
+	}
+	return false
+}
+
+// TagFilter selects stations by tag: any of the groups must match, and a
+// group matches when the station has all of its tags. It is written as
+// "ambient+electronic,jazz" meaning (Ambient AND Electronic) OR Jazz.
+type TagFilter [][]string
+
+// ParseTagFilter reads a filter expression; blank terms are ignored
+func ParseTagFilter(expr string) TagFilter {
+	var filter TagFilter
+	for _, group := range strings.Split(expr, ",") {
+		var terms []string
+		for _, term := range strings.Split(group, "+") {
+			if term = NormalizeTag(term); term != "" {
+				terms = append(terms, term)
+			}
+		}
+		if len(terms) > 0 {
+			filter = append(filter, terms)
+		}
+	}
+	return filter
+}
+
+// Match reports whether a station passes the filter; an empty filter passes everything
+func (f TagFilter) Match(s *RadioStation) bool {
+	if len(f) == 0 {
+		return true
+	}
+	for _, group := range f {
+		all := true
+		for _, tag := range group {
+			if !s.HasTag(tag) {
+				all = false
+				break
+			}
+		}
+		if all {
+			return true
+		}
+	}
+	return false
+}
+
+// Apply returns the stations that pass the filter
+func (f TagFilter) Apply(stations []RadioStation) []RadioStation {
+	if len(f) == 0 {
+		return stations
+	}
+	var matched []RadioStation
+	for i := range stations {
+		if f.Match(&stations[i]) {
+			matched = append(matched, stations[i])
+		}
+	}
+	return matched
+}
+
+// String writes the filter back in the form ParseTagFilter reads
+func (f TagFilter) String() string {
+	groups := make([]string, len(f))
+	for i, group := range f {
+		groups[i] = strings.Join(group, "+")
+	}
+	return strings.Join(groups, ",")
+}
+
+// GetStationsByTag returns stations matching a tag filter expression such as "ambient+electronic"
+func GetStationsByTag(expr string) []RadioStation {
+	return ParseTagFilter(expr).Apply(GetStations())
+}
+
+// GetTags returns every tag in use, genres included, sorted
+func GetTags() []string {
+	seen := make(map[string]bool)
+	var tags []string
+	for _, station := range GetStations() {
+		for _, tag := range station.AllTags() {
+			if !seen[tagKey(tag)] {
+				seen[tagKey(tag)] = true
+				tags = append(tags, tag)
+			}
+		}
+	}
+	sort.Strings(tags)
+	return tags
+}
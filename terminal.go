@@ -0,0 +1,67 @@
+package main
+
+import (
+	"os"
+	"sync"
+)
+
+// overlayFrames is how many frames an overlay is drawn after. The frame the
+// overlay was queued for can be preceded by an older one still waiting to be
+// written, which may rewrite the lines the overlay covers.
+const overlayFrames = 2
+
+// terminalOutput is the program's output. Every write goes through one lock, so
+// escapes sent from commands, such as image uploads, never land inside a frame
+// the renderer is writing. It stays an *os.File underneath, so the terminal is
+// still detected as one.
+type terminalOutput struct {
+	*os.File
+	mu         sync.Mutex
+	overlay    string // Drawn after the next frames, outside the text layout
+	overlayKey string // What the current overlay was queued for
+	pending    int    // Frames the overlay is still drawn after
+}
+
+// Write writes a frame, followed by the overlay while one is pending. The
+// renderer writes each frame with a single Write; its other escapes go
+// through WriteString.
+func (o *terminalOutput) Write(p []byte) (int, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	n, err := o.File.Write(p)
+	if err == nil && o.pending > 0 {
+		o.pending--
+		_, err = o.File.WriteString(o.overlay)
+	}
+	return n, err
+}
+
+// WriteString writes s without drawing the overlay
+func (o *terminalOutput) WriteString(s string) (int, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.File.WriteString(s)
+}
+
+// setOverlay queues seq to be drawn after the next frames. Nothing is queued
+// when key is the one the current overlay was queued for; an empty key
+// drops the overlay.
+func (o *terminalOutput) setOverlay(key, seq string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if key == o.overlayKey {
+		return
+	}
+	o.overlayKey, o.overlay, o.pending = key, seq, 0
+	if key != "" {
+		o.pending = overlayFrames
+	}
+}
+
+// resetOverlay makes the next setOverlay queue its overlay again, for when
+// the whole screen is redrawn
+func (o *terminalOutput) resetOverlay() {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.overlayKey = ""
+}
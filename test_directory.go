@@ -0,0 +1,246 @@
+// +build ignore
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// End-to-end test of the directory client against a fake Radio Browser server.
+// It builds goradio, runs "goradio search" against the fake and checks the
+// requests it sends, the results it prints and the stations -save writes.
+// Nothing outside a temporary directory is touched.
+//
+//	go run test_directory.go
+func main() {
+	failed := 0
+	fail := func(format string, args ...interface{}) {
+		failed++
+		fmt.Printf("FAIL "+format+"\n", args...)
+	}
+
+	var queries []url.Values
+	api := http.NewServeMux()
+	api.HandleFunc("/json/stations/search", func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.Header.Get("User-Agent"), "goradio-hub/") {
+			fail("request without a goradio-hub User-Agent: %q", r.Header.Get("User-Agent"))
+		}
+		queries = append(queries, r.URL.Query())
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, directoryFixture)
+	})
+	api.HandleFunc("/down/json/stations/search", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "maintenance", http.StatusServiceUnavailable)
+	})
+	server := httptest.NewServer(api)
+	defer server.Close()
+
+	dir, err := os.MkdirTemp("", "goradio-directory-test")
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	defer os.RemoveAll(dir)
+
+	binary := filepath.Join(dir, "goradio")
+	if out, err := exec.Command("go", "build", "-o", binary, ".").CombinedOutput(); err != nil {
+		fmt.Printf("building goradio: %v\n%s", err, out)
+		os.Exit(1)
+	}
+	run := func(args ...string) (string, string, int) {
+		cmd := exec.Command(binary, args...)
+		cmd.Env = append(os.Environ(),
+			"HOME="+dir,
+			"XDG_CONFIG_HOME="+filepath.Join(dir, "config"),
+			"XDG_STATE_HOME="+filepath.Join(dir, "state"),
+			"XDG_CACHE_HOME="+filepath.Join(dir, "cache"),
+			"XDG_DATA_HOME="+filepath.Join(dir, "data"),
+		)
+		var stdout, stderr strings.Builder
+		cmd.Stdout, cmd.Stderr = &stdout, &stderr
+		err := cmd.Run()
+		code := 0
+		if exit, ok := err.(*exec.ExitError); ok {
+			code = exit.ExitCode()
+		} else if err != nil {
+			code = -1
+		}
+		return stdout.String(), stderr.String(), code
+	}
+
+	// A search sends the filters, asks for working stations, most voted first
+	stdout, stderr, code := run("search", "-directory", server.URL+"/", "-tag", "jazz", "-country", "nl", "-limit", "5")
+	if code != 0 {
+		fail("search exited with %d: %s", code, stderr)
+	}
+	if len(queries) != 1 {
+		fail("search made %d requests, want 1", len(queries))
+	} else {
+		want := map[string]string{
+			"tag": "jazz", "countrycode": "NL", "order": "votes", "reverse": "true",
+			"hidebroken": "true", "limit": "5",
+		}
+		for key, value := range want {
+			if got := queries[0].Get(key); got != value {
+				fail("search: %s=%q, want %q", key, got, value)
+			}
+		}
+		if queries[0].Has("name") || queries[0].Has("country") {
+			fail("search: empty or replaced filters were sent: %v", queries[0])
+		}
+	}
+	if lines := strings.Split(strings.TrimSpace(stdout), "\n"); len(lines) != 4 {
+		fail("search printed %d results, want 4:\n%s", len(lines), stdout)
+	} else if !strings.Contains(lines[0], "Smooth Jazz Amsterdam") {
+		fail("search: first result is %q, want the most voted station", lines[0])
+	}
+	if failed == 0 {
+		fmt.Println("ok   search sends the query and prints the results")
+	}
+
+	// Country names and click ranking use their own parameters
+	queries = nil
+	if _, stderr, code := run("search", "-directory", server.URL, "-country", "Netherlands", "-order", "clicks"); code != 0 {
+		fail("search by country name exited with %d: %s", code, stderr)
+	} else if len(queries) != 1 || queries[0].Get("country") != "Netherlands" || queries[0].Get("order") != "clickcount" {
+		fail("search by country name sent %v", queries)
+	} else {
+		fmt.Println("ok   country names and click ranking")
+	}
+
+	// -save converts the results into library stations
+	before := failed
+	if _, stderr, code := run("search", "-directory", server.URL, "-save"); code != 0 {
+		fail("search -save exited with %d: %s", code, stderr)
+	} else if !strings.Contains(stderr, "3 added, 0 duplicates skipped, 1 invalid") {
+		fail("search -save reported %q", strings.TrimSpace(stderr))
+	}
+	saved := readSavedStations(filepath.Join(dir, "config", "goradio-hub", "stations.json"), fail)
+	if s, ok := saved["smooth-jazz-amsterdam"]; !ok {
+		fail("saved stations lack smooth-jazz-amsterdam: %v", saved)
+	} else {
+		check := func(field, got, want string) {
+			if got != want {
+				fail("smooth-jazz-amsterdam: %s is %q, want %q", field, got, want)
+			}
+		}
+		check("url", s.URL, "https://stream.example.nl/smoothjazz.aac")
+		check("genre", s.Genre, "Jazz")
+		check("tags", strings.Join(s.Tags, ","), "Smooth Jazz")
+		check("homepage", s.Homepage, "https://smoothjazz.example.nl")
+		check("logo", s.Logo, "https://smoothjazz.example.nl/logo.png")
+		check("description", s.Description, "The Netherlands · dutch")
+		if len(s.Streams) != 1 || s.Streams[0].Codec != "aacp" || s.Streams[0].Bitrate != 64 {
+			fail("smooth-jazz-amsterdam: streams are %+v, want one aacp 64 kbps variant", s.Streams)
+		}
+	}
+	if s, ok := saved["kink-jazz"]; !ok {
+		fail("saved stations lack kink-jazz: %v", saved)
+	} else {
+		if s.URL != "http://kink.example/jazz.mp3" {
+			fail("kink-jazz: url is %q, want the unresolved url", s.URL)
+		}
+		if s.Logo != "" || s.Homepage != "" || len(s.Streams) != 0 {
+			fail("kink-jazz: kept unusable fields: %+v", s)
+		}
+	}
+	if failed == before {
+		fmt.Println("ok   -save converts results into stations")
+	}
+
+	// Saving again finds every station already in the library
+	if _, stderr, _ := run("search", "-directory", server.URL, "-save"); !strings.Contains(stderr, "0 added, 3 duplicates skipped") {
+		fail("saving twice reported %q", strings.TrimSpace(stderr))
+	} else {
+		fmt.Println("ok   saving twice skips duplicates")
+	}
+
+	// A server error is reported, not mistaken for an empty result
+	if _, stderr, code := run("search", "-directory", server.URL+"/down"); code != 1 || !strings.Contains(stderr, "503") {
+		fail("search against a failing server exited with %d: %q", code, strings.TrimSpace(stderr))
+	} else {
+		fmt.Println("ok   server errors are reported")
+	}
+
+	if failed > 0 {
+		fmt.Printf("%d failed\n", failed)
+		os.Exit(1)
+	}
+	fmt.Println("All directory checks passed")
+}
+
+// savedStation is the part of a stations.json entry the checks look at
+type savedStation struct {
+	ID          string   `json:"id"`
+	Name        string   `json:"name"`
+	URL         string   `json:"url"`
+	Genre       string   `json:"genre"`
+	Tags        []string `json:"tags"`
+	Description string   `json:"description"`
+	Logo        string   `json:"logo"`
+	Homepage    string   `json:"homepage"`
+	Streams     []struct {
+		URL     string `json:"url"`
+		Codec   string `json:"codec"`
+		Bitrate int    `json:"bitrate"`
+	} `json:"streams"`
+}
+
+// readSavedStations reads a station file, keyed by station id
+func readSavedStations(path string, fail func(string, ...interface{})) map[string]savedStation {
+	stations := make(map[string]savedStation)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		fail("reading the saved stations: %v", err)
+		return stations
+	}
+	var file struct {
+		Stations []savedStation `json:"stations"`
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		fail("parsing the saved stations: %v", err)
+		return stations
+	}
+	for _, s := range file.Stations {
+		stations[s.ID] = s
+	}
+	return stations
+}
+
+// directoryFixture is a search answer as Radio Browser sends it: one complete
+// entry, one with only the fields older entries have, one without a stream
+// and one more to fill the list
+const directoryFixture = `[
+  {
+    "stationuuid": "9a1b2c3d-0001", "name": "  Smooth Jazz Amsterdam ",
+    "url": "https://smoothjazz.example.nl/listen.pls",
+    "url_resolved": "https://stream.example.nl/smoothjazz.aac",
+    "homepage": "https://smoothjazz.example.nl", "favicon": "https://smoothjazz.example.nl/logo.png",
+    "tags": "jazz,smooth jazz, ", "country": "The Netherlands", "countrycode": "NL",
+    "language": "dutch", "codec": "AAC+", "bitrate": 64, "votes": 812, "clickcount": 90
+  },
+  {
+    "stationuuid": "9a1b2c3d-0002", "name": "KINK Jazz", "url": "http://kink.example/jazz.mp3",
+    "url_resolved": "", "homepage": "kink.example", "favicon": "data:image/png;base64,AAAA",
+    "tags": "", "country": "", "countrycode": "", "language": "",
+    "codec": "UNKNOWN", "bitrate": 0, "votes": 400, "clickcount": 300
+  },
+  {
+    "stationuuid": "9a1b2c3d-0003", "name": "No Stream", "url": "", "url_resolved": "",
+    "tags": "jazz", "codec": "MP3", "bitrate": 128, "votes": 10, "clickcount": 1
+  },
+  {
+    "stationuuid": "9a1b2c3d-0004", "name": "Jazz Radio Rotterdam",
+    "url": "https://rotterdam.example/jazz", "url_resolved": "https://rotterdam.example/jazz",
+    "tags": "jazz,bebop", "country": "The Netherlands", "language": "dutch,english",
+    "codec": "MP3", "bitrate": 192, "votes": 5, "clickcount": 2
+  }
+]`
@@ -8,6 +8,8 @@ import (
 )
 
 // Simple test program to display all logos
+//
+//	go run test_logos.go logo.go theme.go
 func main() {
 	fmt.Println("🎵 GoRadio Hub Logo Gallery 🎵")
 	fmt.Println("==========================\n")
@@ -0,0 +1,101 @@
+// +build ignore
+
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Golden tests for the station file migrations. Each testdata/migrations/vN-*.json
+// is upgraded one step, to version N+1, and compared with the .golden file next to it.
+//
+//	go run test_migrations.go migrations.go          # check
+//	go run test_migrations.go migrations.go -update  # rewrite the golden files
+func main() {
+	update := flag.Bool("update", false, "rewrite the golden files from the current migrations")
+	flag.Parse()
+
+	inputs, _ := filepath.Glob(filepath.Join("testdata", "migrations", "v*.json"))
+	failed := 0
+	fail := func(format string, args ...interface{}) {
+		failed++
+		fmt.Printf("FAIL "+format+"\n", args...)
+	}
+
+	for _, input := range inputs {
+		name := filepath.Base(input)
+		from, err := strconv.Atoi(strings.TrimPrefix(strings.SplitN(name, "-", 2)[0], "v"))
+		if err != nil || from >= stationFileVersion {
+			fail("%s: name must start with v1-v%d", name, stationFileVersion-1)
+			continue
+		}
+		data, err := os.ReadFile(input)
+		if err != nil {
+			fail("%s: %v", name, err)
+			continue
+		}
+
+		got, version, err := MigrateStationFile(data, from+1)
+		if err != nil {
+			fail("%s: %v", name, err)
+			continue
+		}
+		if version != from {
+			fail("%s: detected version %d, want %d", name, version, from)
+		}
+
+		golden := strings.TrimSuffix(input, ".json") + ".golden"
+		if *update {
+			if err := os.WriteFile(golden, got, 0644); err != nil {
+				fail("%s: %v", name, err)
+			}
+			continue
+		}
+		want, err := os.ReadFile(golden)
+		if err != nil {
+			fail("%s: %v (run with -update to create it)", name, err)
+			continue
+		}
+		if !bytes.Equal(got, want) {
+			fail("%s: step %d→%d differs from %s:\n%s", name, from, from+1, filepath.Base(golden), got)
+			continue
+		}
+
+		// Upgrading again must not change anything, and the whole chain must succeed
+		if again, _, err := MigrateStationFile(want, from+1); err != nil || !bytes.Equal(again, want) {
+			fail("%s: upgrading the golden file again changed it (%v)", name, err)
+		}
+		if _, _, err := MigrateStationFile(data, stationFileVersion); err != nil {
+			fail("%s: upgrading to version %d: %v", name, stationFileVersion, err)
+		}
+		fmt.Printf("ok   %s (v%d → v%d)\n", name, from, from+1)
+	}
+
+	// Files from a newer build are refused
+	newer := []byte(fmt.Sprintf(`{"version": %d, "stations": []}`, stationFileVersion+1))
+	if _, _, err := MigrateStationFile(newer, stationFileVersion); err == nil {
+		fail("a version %d file was accepted", stationFileVersion+1)
+	}
+
+	// Every migration step needs at least one golden test
+	for v := 1; v < stationFileVersion; v++ {
+		cases, _ := filepath.Glob(filepath.Join("testdata", "migrations", fmt.Sprintf("v%d-*.json", v)))
+		if len(cases) == 0 {
+			fail("no golden tests for the version %d→%d step", v, v+1)
+		}
+	}
+
+	if failed > 0 {
+		fmt.Printf("%d failed\n", failed)
+		os.Exit(1)
+	}
+	if !*update {
+		fmt.Println("All station file migrations match their golden files")
+	}
+}
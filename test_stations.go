@@ -3,45 +3,102 @@
 package main
 
 import (
+	"encoding/xml"
 	"fmt"
 	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
 )
 
-// Simple test program to verify stations are loaded correctly
+// Simple test program to verify stations are loaded correctly. It builds
+// goradio and reads the built-in stations back through "goradio export", in
+// a temporary home so a local stations.json does not get in the way.
+//
+//	go run test_stations.go
 func main() {
-	stations := GetStations()
-	
+	dir, err := os.MkdirTemp("", "goradio-stations-test")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	binary := filepath.Join(dir, "goradio")
+	if out, err := exec.Command("go", "build", "-o", binary, ".").CombinedOutput(); err != nil {
+		log.Fatalf("building goradio: %v\n%s", err, out)
+	}
+	export := func(args ...string) string {
+		cmd := exec.Command(binary, append([]string{"export"}, args...)...)
+		cmd.Env = append(os.Environ(),
+			"HOME="+dir,
+			"XDG_CONFIG_HOME="+filepath.Join(dir, "config"),
+			"XDG_STATE_HOME="+filepath.Join(dir, "state"),
+			"XDG_CACHE_HOME="+filepath.Join(dir, "cache"),
+			"XDG_DATA_HOME="+filepath.Join(dir, "data"),
+		)
+		cmd.Stderr = os.Stderr
+		out, err := cmd.Output()
+		if err != nil {
+			log.Fatalf("goradio export %s: %v", strings.Join(args, " "), err)
+		}
+		return string(out)
+	}
+
+	var doc struct {
+		Genres []struct {
+			Name     string `xml:"text,attr"`
+			Stations []struct {
+				Name        string `xml:"text,attr"`
+				URL         string `xml:"URL,attr"`
+				Description string `xml:"description,attr"`
+			} `xml:"outline"`
+		} `xml:"body>outline"`
+	}
+	if err := xml.Unmarshal([]byte(export("-format", "opml")), &doc); err != nil {
+		log.Fatalf("reading the exported stations: %v", err)
+	}
+
+	total := 0
+	for _, genre := range doc.Genres {
+		total += len(genre.Stations)
+	}
+
 	fmt.Printf("GoRadio Hub - Station Test\n")
 	fmt.Printf("======================\n\n")
-	fmt.Printf("Total stations loaded: %d\n\n", len(stations))
-	
-	// Group by genre
-	genreCount := make(map[string]int)
-	for _, station := range stations {
-		genreCount[station.Genre]++
+	fmt.Printf("Total stations loaded: %d\n\n", total)
+	if total < 5 {
+		log.Fatalf("expected at least 5 built-in stations, got %d", total)
 	}
-	
+
+	// Group by genre
 	fmt.Printf("Stations by genre:\n")
-	for genre, count := range genreCount {
-		fmt.Printf("  %-20s: %d stations\n", genre, count)
+	for _, genre := range doc.Genres {
+		fmt.Printf("  %-20s: %d stations\n", genre.Name, len(genre.Stations))
 	}
-	
+
 	fmt.Printf("\nFirst 5 stations:\n")
-	for i, station := range stations[:5] {
-		fmt.Printf("%d. %s (%s)\n", i+1, station.Name, station.Genre)
-		fmt.Printf("   URL: %s\n", station.URL)
-		fmt.Printf("   Description: %s\n\n", station.Description)
+	shown := 0
+	for _, genre := range doc.Genres {
+		for _, station := range genre.Stations {
+			if shown == 5 {
+				break
+			}
+			shown++
+			fmt.Printf("%d. %s (%s)\n", shown, station.Name, genre.Name)
+			fmt.Printf("   URL: %s\n", station.URL)
+			fmt.Printf("   Description: %s\n\n", station.Description)
+			if station.URL == "" {
+				log.Fatalf("%s has no stream URL", station.Name)
+			}
+		}
 	}
-	
+
 	// Test genre filtering
-	lofiStations := GetStationsByGenre("Lofi Hip Hop")
-	fmt.Printf("Lofi Hip Hop stations: %d\n", len(lofiStations))
-	
-	vaporwaveStations := GetStationsByGenre("Vaporwave")
-	fmt.Printf("Vaporwave stations: %d\n", len(vaporwaveStations))
-	
-	ambientStations := GetStationsByGenre("Ambient")
-	fmt.Printf("Ambient stations: %d\n", len(ambientStations))
-	
+	for _, genre := range []string{"Lofi Hip Hop", "Vaporwave", "Ambient"} {
+		count := strings.Count(export("-genre", genre), "#EXTINF")
+		fmt.Printf("%s stations: %d\n", genre, count)
+	}
+
 	log.Println("Station test completed successfully!")
 }
@@ -0,0 +1,13 @@
+package main
+
+import "github.com/charmbracelet/lipgloss"
+
+var (
+	// Base colors that adapt to terminal
+	primaryColor   = lipgloss.AdaptiveColor{Light: "#874BFE", Dark: "#7D56F4"}
+	secondaryColor = lipgloss.AdaptiveColor{Light: "#43BF6D", Dark: "#73F59F"}
+	accentColor    = lipgloss.AdaptiveColor{Light: "#F25D94", Dark: "#F25D94"}
+	textColor      = lipgloss.AdaptiveColor{Light: "#0F0F0F", Dark: "#FAFAFA"}
+	mutedColor     = lipgloss.AdaptiveColor{Light: "#6B7280", Dark: "#9CA3AF"}
+	borderColor    = lipgloss.AdaptiveColor{Light: "#D1D5DB", Dark: "#374151"}
+)
@@ -3,79 +3,13 @@ package main
 import (
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/charmbracelet/lipgloss"
 )
 
-// LogoType represents different logo options
-type LogoType int
-
-const (
-	LogoOriginal LogoType = iota
-	LogoPepe
-	LogoNone
-)
-
-func (l LogoType) String() string {
-	switch l {
-	case LogoOriginal:
-		return "GoRadio Hub"
-	case LogoPepe:
-		return "Pepe"
-	case LogoNone:
-		return "None"
-	default:
-		return "GoRadio Hub"
-	}
-}
-
-// ASCII art for GoRadio logo - Compact and clean
-const GoRadioLogo = `
-  ╔══════════════════════════════════════════════════╗
-  ║           G O R A D I O   H U B                  ║
-  ║         ~ Terminal Music Player v1.0 ~           ║
-  ╚══════════════════════════════════════════════════╝
-`
-
-// ASCII art for Pepe the Frog - Perfectly aligned
-const PepeLogo = `
-      ╔═══════════════════════════════════╗
-      ║          ,-._____.-.              ║
-      ║         /  o     o  \             ║
-      ║        |       >      |           ║
-      ║        |   \_______/   |           ║
-      ║         \             /           ║
-      ║          '-.______.-'             ║
-      ║                                   ║
-      ║   🐸 F E E L S  G O O D  M A N   ║
-      ║        ~ Bringing Da Vibes ~      ║
-      ╚═══════════════════════════════════╝
-`
-
 // Style definitions
 var (
-	// Base colors that adapt to terminal
-	primaryColor   = lipgloss.AdaptiveColor{Light: "#874BFE", Dark: "#7D56F4"}
-	secondaryColor = lipgloss.AdaptiveColor{Light: "#43BF6D", Dark: "#73F59F"}
-	accentColor    = lipgloss.AdaptiveColor{Light: "#F25D94", Dark: "#F25D94"}
-	textColor      = lipgloss.AdaptiveColor{Light: "#0F0F0F", Dark: "#FAFAFA"}
-	mutedColor     = lipgloss.AdaptiveColor{Light: "#6B7280", Dark: "#9CA3AF"}
-	borderColor    = lipgloss.AdaptiveColor{Light: "#D1D5DB", Dark: "#374151"}
-
-	// Logo styles
-	logoStyle = lipgloss.NewStyle().
-			Foreground(primaryColor).
-			Bold(true).
-			Align(lipgloss.Center).
-			Margin(1, 0, 2, 0)
-
-	// Pepe style - bold green
-	pepeStyle = lipgloss.NewStyle().
-			Foreground(lipgloss.Color("#00FF00")).
-			Bold(true).
-			Align(lipgloss.Center).
-			Margin(1, 0, 2, 0)
-
 	// Title styles
 	titleStyle = lipgloss.NewStyle().
 			Foreground(primaryColor).
@@ -147,31 +81,6 @@ var (
 			Padding(1, 2)
 )
 
-// RenderLogo renders the selected ASCII logo
-func RenderLogo(logoType LogoType) string {
-	switch logoType {
-	case LogoOriginal:
-		return logoStyle.Render(GoRadioLogo)
-	case LogoPepe:
-		return pepeStyle.Render(PepeLogo)
-	case LogoNone:
-		return ""
-	default:
-		return logoStyle.Render(GoRadioLogo)
-	}
-}
-
-// RenderLogoSelector renders the logo selection info
-func RenderLogoSelector(currentLogo LogoType) string {
-	selectorText := fmt.Sprintf("Logo: %s (Press 'l' to cycle)", currentLogo.String())
-	return lipgloss.NewStyle().
-		Foreground(mutedColor).
-		Italic(true).
-		Align(lipgloss.Center).
-		Margin(0, 0, 1, 0).
-		Render(selectorText)
-}
-
 // RenderTitle renders a styled title
 func RenderTitle(text string) string {
 	return titleStyle.Render(text)
@@ -195,6 +104,9 @@ func RenderStationList(stations []RadioStation, selected int, startIdx int, visi
 	for i := startIdx; i < endIdx; i++ {
 		station := stations[i]
 		prefix := "  "
+		if station.Favorite {
+			prefix = "★ "
+		}
 		
 		// Add numbering
 		line := fmt.Sprintf("%s%d. %s", prefix, i+1, station.Name)
@@ -202,19 +114,53 @@ func RenderStationList(stations []RadioStation, selected int, startIdx int, visi
 		// Add genre info
 		line += fmt.Sprintf(" (%s)", station.Genre)
 		
+		if station.Rating > 0 {
+			line += " " + RenderRating(station.Rating)
+		}
+		
 		if i == selected {
 			line = selectedItemStyle.Render(line)
 		} else {
 			line = normalItemStyle.Render(line)
 		}
-		items = append(items, line)
+		// The badge goes outside the item style so its color survives the highlight
+		items = append(items, RenderHealthBadge(station.Health)+line)
+	}
+	
+	return strings.Join(items, "\n")
+}
+
+// RenderRating shows a 1-5 rating as filled and empty dots
+func RenderRating(rating int) string {
+	return strings.Repeat("●", rating) + strings.Repeat("○", 5-rating)
+}
+
+// RenderRecentList renders recently played stations with when and how long they were listened to
+func RenderRecentList(stations []RadioStation, selected int, startIdx int, visibleCount int, now time.Time) string {
+	var items []string
+	
+	endIdx := min(len(stations), startIdx+visibleCount)
+	for i := startIdx; i < endIdx; i++ {
+		station := stations[i]
+		prefix := "  "
+		if station.Favorite {
+			prefix = "★ "
+		}
+		
+		line := fmt.Sprintf("%s%s · %s · %s total", prefix, station.Name, timeAgo(station.LastPlayed, now), formatListened(station.Listened))
+		if i == selected {
+			line = selectedItemStyle.Render(line)
+		} else {
+			line = normalItemStyle.Render(line)
+		}
+		items = append(items, RenderHealthBadge(station.Health)+line)
 	}
 	
 	return strings.Join(items, "\n")
 }
 
 // RenderNowPlaying renders the currently playing station info
-func RenderNowPlaying(station *RadioStation, song string, status string) string {
+func RenderNowPlaying(station *RadioStation, stream StreamVariant, song string, status string) string {
 	if station == nil {
 		return statusStyle.Render("No station selected")
 	}
@@ -222,6 +168,7 @@ func RenderNowPlaying(station *RadioStation, song string, status string) string
 	content := []string{
 		stationInfoStyle.Render("♪ " + station.Name),
 		fmt.Sprintf("Genre: %s", station.Genre),
+		fmt.Sprintf("Stream: %s", stream),
 		fmt.Sprintf("Status: %s", status),
 	}
 	
@@ -234,7 +181,7 @@ func RenderNowPlaying(station *RadioStation, song string, status string) string
 }
 
 // RenderStationInfo renders detailed station information
-func RenderStationInfo(station *RadioStation) string {
+func RenderStationInfo(station *RadioStation, quality Quality, override bool) string {
 	if station == nil {
 		return lipgloss.NewStyle().Foreground(mutedColor).Render("Select a station to see details")
 	}
@@ -242,16 +189,68 @@ func RenderStationInfo(station *RadioStation) string {
 	content := []string{
 		titleStyle.Render(station.Name),
 		fmt.Sprintf("Genre: %s", station.Genre),
+	}
+	if len(station.Tags) > 0 {
+		content = append(content, "Tags: "+strings.Join(station.Tags, ", "))
+	}
+	if station.Rating > 0 {
+		content = append(content, "Rating: "+RenderRating(station.Rating))
+	}
+	if station.Listeners > 0 {
+		content = append(content, fmt.Sprintf("Listeners: %d", station.Listeners))
+	}
+	if station.Health != "" {
+		content = append(content, "Availability: "+RenderAvailability(station.Health, station.Uptime, station.Checks))
+	}
+	content = append(content,
 		"",
 		"Description:",
 		station.Description,
 		"",
-		fmt.Sprintf("Stream URL: %s", station.URL),
+	)
+	if station.Homepage != "" {
+		content = append(content, "Homepage: "+station.Homepage)
+	}
+	content = append(content, fmt.Sprintf("Stream URL: %s", station.URL))
+	
+	qualityLine := fmt.Sprintf("Quality: %s", quality)
+	if override {
+		qualityLine += " (station override)"
+	}
+	content = append(content, "", qualityLine)
+	
+	if overrides := station.Settings.Overrides(); len(overrides) > 0 {
+		content = append(content, "Playback settings:")
+		for _, line := range overrides {
+			content = append(content, "  "+line)
+		}
+	}
+	
+	if len(station.Streams) > 0 {
+		selected := SelectStream(station, quality)
+		content = append(content, "Streams:")
+		for _, v := range station.Streams {
+			marker := "  "
+			if v.URL == selected.URL {
+				marker = "▶ "
+			}
+			content = append(content, marker+v.String())
+		}
 	}
 	
 	return strings.Join(content, "\n")
 }
 
+// RenderWarmStatus shows whether the highlighted station is ready for instant switching
+func RenderWarmStatus(ready bool, open int, limit int) string {
+	state := "warming after a short pause..."
+	if ready {
+		state = "ready ⚡"
+	}
+	return lipgloss.NewStyle().Foreground(mutedColor).Render(
+		fmt.Sprintf("Warm mode: %s (%d/%d connections)", state, open, limit))
+}
+
 // RenderHelp renders help text
 func RenderHelp() string {
 	helpText := []string{
@@ -259,6 +258,27 @@ func RenderHelp() string {
 		"↑/↓ or j/k  Navigate stations",
 		"Enter/Space  Play/Stop station", 
 		"l           Cycle logo (GoRadio Hub/Pepe/None)",
+		"d           Toggle network/buffer diagnostics",
+		"b           Cycle global stream quality",
+		"B           Cycle quality override for station",
+		"w           Toggle warm mode (preload highlighted)",
+		"o           Choose audio output device",
+		"v           Toggle spectrum visualizer (ffmpeg)",
+		"a/e/x       Add / edit / delete station",
+		"i/E         Import / export (M3U, PLS, OPML)",
+		"/           Search the station directory",
+		"S           Sync the SomaFM catalog",
+		"f/F         Toggle favorite / favorites view",
+		"K/J         Move favorite / profile station up / down",
+		"R           Recently played view",
+		"p           Previous station (last channel)",
+		"1-5         Rate station (same again clears)",
+		"s           Cycle sort order",
+		"t           Filter by tags (a+b, a,b)",
+		"H           Check health of listed stations",
+		"D           Find and merge duplicate stations",
+		"I           Compare with the server's ICY headers and update",
+		"P           Switch station profile",
 		"q           Quit",
 		"?           Toggle this help",
 	}
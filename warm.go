@@ -0,0 +1,237 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Warm mode keeps a muted, paused connection open to the highlighted station so
+// that pressing Enter only has to unpause it.
+
+const (
+	// warmDwell is how long a station must stay highlighted before it is warmed
+	warmDwell = 1500 * time.Millisecond
+	// warmTTL is how long an unused warm connection is kept open
+	warmTTL = 2 * time.Minute
+	// defaultMaxWarm caps the number of concurrent warm connections
+	defaultMaxWarm = 1
+	// defaultWarmCacheKB is the read-ahead of a paused connection; once it is
+	// full the connection idles, so it also bounds what warming downloads
+	defaultWarmCacheKB = 256
+)
+
+// warmStream is a pre-opened, paused mpv instance for one station
+type warmStream struct {
+	key       string
+	streamURL string
+	proc      *mpvProcess
+	lastUsed  time.Time
+}
+
+// warmPool tracks the warm connections owned by a Player
+type warmPool struct {
+	mu      sync.Mutex
+	streams map[string]*warmStream
+	pending map[string]bool
+}
+
+func newWarmPool() *warmPool {
+	return &warmPool{
+		streams: make(map[string]*warmStream),
+		pending: make(map[string]bool),
+	}
+}
+
+// resolveStreamURL picks the variant for quality and unwraps PLS playlists. A
+// playlist that cannot be fetched or read counts as a failed connection, so
+// the same playlist is tried on the station's mirrors before giving up.
+func (p *Player) resolveStreamURL(station *RadioStation, quality Quality) (string, error) {
+	streamURL := SelectStream(station, quality).URL
+	if !strings.HasSuffix(streamURL, ".pls") {
+		return streamURL, nil
+	}
+	var firstErr error
+	for _, candidate := range p.mirrors.Order(mirrorCandidates(streamURL, station.Mirrors)) {
+		actualURL, err := p.parsePLS(candidate, station.Settings)
+		if err == nil {
+			return actualURL, nil
+		}
+		log.Printf("pls: %s: %v", hostOf(candidate), err)
+		p.mirrors.RecordFailure(candidate)
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+	return "", fmt.Errorf("Failed to parse PLS: %v", firstErr)
+}
+
+// Warm pre-resolves the station's playlist and opens a paused, muted connection to it.
+// At most maxWarm connections are kept, counting those still connecting, each
+// limited to cacheKB of read-ahead. The least recently used ready connection
+// makes room for a new one; when every slot is still connecting, nothing is warmed.
+func (p *Player) Warm(station *RadioStation, opts PlayOptions, maxWarm int, cacheKB int) {
+	if maxWarm <= 0 {
+		return
+	}
+	p.mu.Lock()
+	playing := p.currentStation == station && p.state != StateStopped
+	p.mu.Unlock()
+	if playing {
+		return
+	}
+
+	key := station.Key()
+	pool := p.warm
+	pool.mu.Lock()
+	if w, ok := pool.streams[key]; ok {
+		w.lastUsed = time.Now()
+		pool.mu.Unlock()
+		return
+	}
+	if pool.pending[key] {
+		pool.mu.Unlock()
+		return
+	}
+	// Reserve the slot before dialing so connections in flight count towards the cap
+	if len(pool.pending) >= maxWarm {
+		pool.mu.Unlock()
+		return
+	}
+	evicted := pool.evictLocked(maxWarm - 1 - len(pool.pending))
+	pool.pending[key] = true
+	pool.mu.Unlock()
+	killWarm(evicted)
+
+	go func() {
+		defer func() {
+			pool.mu.Lock()
+			delete(pool.pending, key)
+			pool.mu.Unlock()
+		}()
+
+		streamURL, err := p.resolveStreamURL(station, opts.Quality)
+		if err != nil {
+			log.Printf("warm: %s: %v", station.Name, err)
+			return
+		}
+		if candidates := p.mirrors.Order(mirrorCandidates(streamURL, station.Mirrors)); len(candidates) > 0 {
+			streamURL = candidates[0]
+		}
+
+		args := append([]string{
+			"--pause=yes",
+			"--mute=yes",
+			fmt.Sprintf("--demuxer-max-bytes=%dKiB", cacheKB),
+			"--demuxer-readahead-secs=5",
+		}, opts.mpvArgs()...)
+		proc, err := startMPVProcess(streamURL, args...)
+		if err != nil {
+			log.Printf("warm: %s: %v", station.Name, err)
+			return
+		}
+		if err := proc.connect(5 * time.Second); err != nil {
+			log.Printf("warm: %s: %v", station.Name, err)
+			proc.kill()
+			return
+		}
+
+		pool.mu.Lock()
+		delete(pool.pending, key)
+		pool.streams[key] = &warmStream{key: key, streamURL: streamURL, proc: proc, lastUsed: time.Now()}
+		evicted := pool.evictLocked(maxWarm - len(pool.pending))
+		pool.mu.Unlock()
+		killWarm(evicted)
+		log.Printf("warm: %s ready on %s", station.Name, hostOf(streamURL))
+	}()
+}
+
+// killWarm closes evicted connections; it is called without the pool lock held
+func killWarm(evicted []*warmStream) {
+	for _, w := range evicted {
+		w.proc.kill()
+	}
+}
+
+// evictLocked drops the least recently used connections above the cap and any that died
+func (w *warmPool) evictLocked(maxWarm int) []*warmStream {
+	var evicted []*warmStream
+	for key, s := range w.streams {
+		if s.proc.exited() || time.Since(s.lastUsed) > warmTTL {
+			evicted = append(evicted, s)
+			delete(w.streams, key)
+		}
+	}
+	for len(w.streams) > maxWarm {
+		var oldest *warmStream
+		for _, s := range w.streams {
+			if oldest == nil || s.lastUsed.Before(oldest.lastUsed) {
+				oldest = s
+			}
+		}
+		evicted = append(evicted, oldest)
+		delete(w.streams, oldest.key)
+	}
+	return evicted
+}
+
+// takeWarm removes and returns the warm connection for a station if it matches streamURL
+func (p *Player) takeWarm(station *RadioStation, streamURL string) *mpvProcess {
+	p.warm.mu.Lock()
+	defer p.warm.mu.Unlock()
+
+	w, ok := p.warm.streams[station.Key()]
+	if !ok {
+		return nil
+	}
+	delete(p.warm.streams, station.Key())
+
+	if w.proc.exited() || !sameStream(w.streamURL, streamURL, station.Mirrors) {
+		w.proc.kill()
+		return nil
+	}
+	return w.proc
+}
+
+// sameStream reports whether a warm URL serves the requested stream, possibly from a mirror
+func sameStream(warmURL, streamURL string, mirrors []string) bool {
+	for _, candidate := range mirrorCandidates(streamURL, mirrors) {
+		if candidate == warmURL {
+			return true
+		}
+	}
+	return false
+}
+
+// ExpireWarm closes warm connections that have not been used recently
+func (p *Player) ExpireWarm(maxWarm int) {
+	p.warm.mu.Lock()
+	evicted := p.warm.evictLocked(maxWarm)
+	p.warm.mu.Unlock()
+
+	for _, w := range evicted {
+		w.proc.kill()
+	}
+}
+
+// DropWarm closes every warm connection
+func (p *Player) DropWarm() {
+	p.ExpireWarm(0)
+}
+
+// WarmCount returns how many warm connections are open
+func (p *Player) WarmCount() int {
+	p.warm.mu.Lock()
+	defer p.warm.mu.Unlock()
+	return len(p.warm.streams)
+}
+
+// IsWarm reports whether the station has a warm connection ready
+func (p *Player) IsWarm(station *RadioStation) bool {
+	p.warm.mu.Lock()
+	defer p.warm.mu.Unlock()
+	_, ok := p.warm.streams[station.Key()]
+	return ok
+}